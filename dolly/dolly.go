@@ -5,11 +5,13 @@ import (
 	"flag"
 	"fmt"
 	"image"
-	"image/color"
 	"image/color/palette"
+	"image/draw"
 	"image/gif"
 	"io/ioutil"
 	"math"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/DheerendraRathor/GoTracer/models"
@@ -36,6 +38,7 @@ func main() {
 
 	var env models.Specification
 	json.Unmarshal(file, &env)
+	env.Image.Validate()
 
 	outputFileFormat := env.Image.OutputFile
 
@@ -45,6 +48,7 @@ func main() {
 	initialZDistance := cameraInput.LookFrom[2]
 	initialFieldOfView := cameraInput.FieldOfView
 	initialFocus := cameraInput.Focus
+	shutterOpen, shutterClose := cameraInput.ShutterOpen, cameraInput.ShutterClose
 
 	for zoomMode := 0; zoomMode < 2; zoomMode++ {
 		directoryName := "out"
@@ -59,19 +63,24 @@ func main() {
 
 		outGif := &gif.GIF{}
 		env.Image.OutputFile = fmt.Sprintf(outputFileFormat, directoryName)
+		aovs := env.Image.AOVs
+		aovGifs := make([]*gif.GIF, len(aovs))
+		for k := range aovGifs {
+			aovGifs[k] = &gif.GIF{}
+		}
 
 		for i := 0; i < 40; i++ {
 			fmt.Printf("Rendering frame: %d. ZoomMode: Zoom %s\n", i, directoryName)
 			env.Scene.Camera = cameraInput
 
-			progress := make(chan *models.Pixel, 1000)
+			// Scene time advances by one per frame so moving objects animate
+			// across frames and blur within each frame's shutter interval.
+			env.Scene.Camera.ShutterOpen = shutterOpen + float64(i)
+			env.Scene.Camera.ShutterClose = shutterClose + float64(i)
 
-			imageRect := image.Rectangle{
-				Min: image.Point{},
-				Max: image.Point{X: env.Image.Width, Y: env.Image.Height},
-			}
+			progress := make(chan *models.Pixel, 1000)
 
-			palleted := image.NewPaletted(imageRect, palette.Plan9)
+			framebuffers := models.NewFramebuffers(&env.Image)
 
 			var pbWg sync.WaitGroup
 			var progressBar *pb.ProgressBar
@@ -91,8 +100,7 @@ func main() {
 						break
 					}
 
-					rgbaColor := color.RGBA{R: pixel.Color[0], G: pixel.Color[1], B: pixel.Color[2], A: 255}
-					palleted.Set(pixel.I, pixel.J, rgbaColor)
+					framebuffers.SetPixel(pixel)
 
 					if showProgress {
 						progressBar.Increment()
@@ -106,8 +114,21 @@ func main() {
 			close(progress)
 			progressBar.Finish()
 
-			outGif.Image = append(outGif.Image, palleted)
-			outGif.Delay = append(outGif.Delay, 0)
+			// HDR formats can't be animated, so each frame goes to its own file
+			// numbered before the extension.
+			if env.Image.GetFormat() != models.PNGFormat {
+				extension := filepath.Ext(env.Image.OutputFile)
+				frameFileName := fmt.Sprintf("%s_%03d%s", strings.TrimSuffix(env.Image.OutputFile, extension), i, extension)
+				if err := framebuffers.WriteFiles(&env.Image, frameFileName); err != nil {
+					panic(fmt.Sprintf("Unable to write frame: %v\n", err))
+				}
+			} else {
+				addGifFrame(outGif, framebuffers.Image, env.Image.GetToneMapper())
+				for k, name := range aovs {
+					preview, mapper := env.Image.AOVPreview(name, framebuffers.AOVs[k])
+					addGifFrame(aovGifs[k], preview, mapper)
+				}
+			}
 
 			// Changing camera distance and maintaining FoV
 			cameraInput.LookFrom[2] += distanceChange
@@ -118,8 +139,25 @@ func main() {
 			cameraInput.Focus = newFocus
 		}
 
-		gifFile := utils.CreateNestedFile(env.Image.OutputFile)
-		gif.EncodeAll(gifFile, outGif)
-		gifFile.Close()
+		if len(outGif.Image) > 0 {
+			writeGif(env.Image.OutputFile, outGif)
+			for k, name := range aovs {
+				writeGif(models.AOVFileName(env.Image.OutputFile, name), aovGifs[k])
+			}
+		}
 	}
 }
+
+func addGifFrame(outGif *gif.GIF, frame *models.Image, mapper *models.ToneMapper) {
+	bounds := image.Rect(0, 0, frame.Width, frame.Height)
+	palleted := image.NewPaletted(bounds, palette.Plan9)
+	draw.Draw(palleted, bounds, frame.Quantize(mapper), image.Point{}, draw.Src)
+	outGif.Image = append(outGif.Image, palleted)
+	outGif.Delay = append(outGif.Delay, 0)
+}
+
+func writeGif(fileName string, outGif *gif.GIF) {
+	gifFile := utils.CreateNestedFile(fileName)
+	gif.EncodeAll(gifFile, outGif)
+	gifFile.Close()
+}
@@ -4,9 +4,6 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
 	"io/ioutil"
 	"log"
 	"os"
@@ -15,7 +12,6 @@ import (
 
 	"github.com/DheerendraRathor/GoTracer/models"
 	"github.com/DheerendraRathor/GoTracer/tracer"
-	"github.com/DheerendraRathor/GoTracer/utils"
 	"gopkg.in/cheggaaa/pb.v1"
 )
 
@@ -51,11 +47,9 @@ func main() {
 
 	var env models.Specification
 	json.Unmarshal(file, &env)
+	env.Image.Validate()
 
-	pngImage := image.NewRGBA(image.Rectangle{
-		image.Point{0, 0},
-		image.Point{env.Image.Width, env.Image.Height},
-	})
+	framebuffers := models.NewFramebuffers(&env.Image)
 
 	if showProgress {
 		progress := make(chan *models.Pixel, 100)
@@ -79,7 +73,7 @@ func main() {
 					break
 				}
 
-				updateImage(pngImage, pixel)
+				framebuffers.SetPixel(pixel)
 				progressBar.Increment()
 			}
 		}()
@@ -90,23 +84,11 @@ func main() {
 		progressBar.Finish()
 	} else {
 		tracerOutput := tracer.GoTrace(&env, false, nil, false, nil)
-
-		for _, row := range tracerOutput.Pixels {
-			for _, pixel := range row {
-				updateImage(pngImage, pixel)
-			}
-		}
-
+		framebuffers = tracerOutput.Framebuffers
 	}
 
-	pngFile := utils.CreateNestedFile(env.Image.OutputFile)
-	defer pngFile.Close()
-
-	png.Encode(pngFile, pngImage)
-
-}
+	if err := framebuffers.WriteFiles(&env.Image, env.Image.OutputFile); err != nil {
+		log.Fatal("could not write image: ", err)
+	}
 
-func updateImage(image *image.RGBA, pixel *models.Pixel) {
-	rgbaColor := color.RGBA{pixel.Color[0], pixel.Color[1], pixel.Color[2], 255}
-	image.Set(pixel.I, pixel.J, rgbaColor)
 }
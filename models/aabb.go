@@ -0,0 +1,81 @@
+package models
+
+import "math"
+
+type AABB struct {
+	Min, Max *Vector
+}
+
+func NewAABB(min, max *Vector) *AABB {
+	return &AABB{
+		Min: min,
+		Max: max,
+	}
+}
+
+// Hit performs the slab test against all three axes and reports whether the
+// ray overlaps the box anywhere in (tmin, tmax).
+func (b *AABB) Hit(r *Ray, tmin, tmax float64) bool {
+	hit, _, _ := b.Clip(r, tmin, tmax)
+	return hit
+}
+
+// Clip narrows (tmin, tmax) to the part of the ray inside the box.
+func (b *AABB) Clip(r *Ray, tmin, tmax float64) (bool, float64, float64) {
+	for a := 0; a < 3; a++ {
+		invD := 1 / r.Direction.data[a]
+		t0 := (b.Min.data[a] - r.Origin.data[a]) * invD
+		t1 := (b.Max.data[a] - r.Origin.data[a]) * invD
+		if invD < 0 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tmin {
+			tmin = t0
+		}
+		if t1 < tmax {
+			tmax = t1
+		}
+		if tmax <= tmin {
+			return false, 0, 0
+		}
+	}
+	return true, tmin, tmax
+}
+
+// Pad widens any axis thinner than delta so that flat objects still have a
+// box with non-zero volume for the slab test.
+func (b *AABB) Pad(delta float64) *AABB {
+	for a := 0; a < 3; a++ {
+		if b.Max.data[a]-b.Min.data[a] < delta {
+			b.Min.data[a] -= delta / 2
+			b.Max.data[a] += delta / 2
+		}
+	}
+	return b
+}
+
+func (b *AABB) Centroid() *Vector {
+	return b.Min.Copy().AddVector(b.Max).Scale(0.5)
+}
+
+func (b *AABB) SurfaceArea() float64 {
+	dx := b.Max.data[0] - b.Min.data[0]
+	dy := b.Max.data[1] - b.Min.data[1]
+	dz := b.Max.data[2] - b.Min.data[2]
+	return 2 * (dx*dy + dy*dz + dz*dx)
+}
+
+func SurroundingBox(b0, b1 *AABB) *AABB {
+	return NewAABB(
+		NewVector(
+			math.Min(b0.Min.data[0], b1.Min.data[0]),
+			math.Min(b0.Min.data[1], b1.Min.data[1]),
+			math.Min(b0.Min.data[2], b1.Min.data[2]),
+		),
+		NewVector(
+			math.Max(b0.Max.data[0], b1.Max.data[0]),
+			math.Max(b0.Max.data[1], b1.Max.data[1]),
+			math.Max(b0.Max.data[2], b1.Max.data[2]),
+		),
+	)
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/DheerendraRathor/GoTracer/utils"
+)
+
+// Arbitrary output variables, or AOVs, are extra passes rendered alongside
+// the image. All but Direct and Indirect describe the first surface each
+// camera ray hits, and are black where it hits nothing.
+const (
+	DepthAOV      = "Depth"
+	NormalAOV     = "Normal"
+	AlbedoAOV     = "Albedo"
+	ObjectIDAOV   = "ObjectID"
+	MaterialIDAOV = "MaterialID"
+	PositionAOV   = "Position"
+	DirectAOV     = "Direct"
+	IndirectAOV   = "Indirect"
+)
+
+// validateAOVs panics on unknown passes, and on ID passes in HDR files whose
+// 8 bit mantissas can't hold the IDs.
+func (i *ImageInput) validateAOVs() {
+	for _, name := range i.AOVs {
+		switch name {
+		case DepthAOV, NormalAOV, AlbedoAOV, ObjectIDAOV, MaterialIDAOV, PositionAOV, DirectAOV, IndirectAOV:
+		default:
+			panic(fmt.Sprintf("Got invalid AOV: %s", name))
+		}
+		if IsIDAOV(name) && i.GetFormat() == HDRFormat {
+			panic(fmt.Sprintf("AOV %s can't be written as HDR", name))
+		}
+	}
+}
+
+// HasAOV reports whether the image asks for the pass name.
+func (i *ImageInput) HasAOV(name string) bool {
+	for _, aov := range i.AOVs {
+		if aov == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIDAOV reports whether the pass name holds IDs, which can't be averaged
+// over samples.
+func IsIDAOV(name string) bool {
+	return name == ObjectIDAOV || name == MaterialIDAOV
+}
+
+// AOVFileName returns the file a pass is written to, next to outputFile with
+// the pass name before the extension.
+func AOVFileName(outputFile, name string) string {
+	extension := filepath.Ext(outputFile)
+	return fmt.Sprintf("%s_%s%s", strings.TrimSuffix(outputFile, extension), strings.ToLower(name), extension)
+}
+
+// SurfaceAOV returns the value of a first hit pass for ray, which hit nothing
+// when hitRecord is nil.
+func SurfaceAOV(name string, ray *Ray, hitRecord *HitRecord, camera *Camera) *Vector {
+	if hitRecord == nil {
+		return NewEmptyVector()
+	}
+
+	switch name {
+	case DepthAOV:
+		depth := hitRecord.P.Copy().SubtractVector(camera.Origin).Dot(camera.W)
+		return NewVector(-depth, -depth, -depth)
+	case NormalAOV:
+		return hitRecord.FacingNormal(ray).Copy()
+	case AlbedoAOV:
+		if material, ok := hitRecord.Material.(baseMaterial); ok {
+			return material.base().albedo(hitRecord)
+		}
+	case ObjectIDAOV:
+		return idValue(hitRecord.ObjectID)
+	case MaterialIDAOV:
+		if material, ok := hitRecord.Material.(baseMaterial); ok {
+			return idValue(material.base().ID)
+		}
+	case PositionAOV:
+		return hitRecord.P.Copy()
+	}
+	return NewEmptyVector()
+}
+
+func idValue(id uint32) *Vector {
+	return NewVector(float64(id), float64(id), float64(id))
+}
+
+// idColor spreads IDs over distinct colours, keeping 0 black.
+func idColor(id uint32) *Vector {
+	if id == 0 {
+		return NewEmptyVector()
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte{byte(id), byte(id >> 8), byte(id >> 16), byte(id >> 24)})
+	h := hash.Sum32()
+	return NewVector(float64(h&0xff), float64(h>>8&0xff), float64(h>>16&0xff)).Scale(1.0 / 255)
+}
+
+// id derives a material ID from the surface description, so equal surfaces
+// share an ID across frames and agents. It is kept to 24 bits, which the 32
+// bit floats of PFM and full float EXR hold exactly.
+func (s *SurfaceInput) id() uint32 {
+	description, _ := json.Marshal(s)
+	hash := fnv.New32a()
+	hash.Write(description)
+	return hash.Sum32()&0xffffff | 1
+}
+
+// identified tags the hits of a top level object with its ID.
+type identified struct {
+	Hitable
+	id uint32
+}
+
+func (o *identified) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	hit, record := o.Hitable.Hit(r, tmin, tmax)
+	if hit {
+		record.ObjectID = o.id
+	}
+	return hit, record
+}
+
+// identify numbers the objects from 1 in the order they are listed.
+func identify(objects []Hitable) []Hitable {
+	identifiedObjects := make([]Hitable, len(objects))
+	for k, object := range objects {
+		identifiedObjects[k] = &identified{object, uint32(k + 1)}
+	}
+	return identifiedObjects
+}
+
+// Framebuffers holds the rendered image along with an image for each pass,
+// in the order the passes are asked for.
+type Framebuffers struct {
+	Image *Image
+	AOVs  []*Image
+}
+
+func NewFramebuffers(input *ImageInput) *Framebuffers {
+	framebuffers := &Framebuffers{
+		Image: NewFramebuffer(input.Width, input.Height),
+	}
+	for range input.AOVs {
+		framebuffers.AOVs = append(framebuffers.AOVs, NewFramebuffer(input.Width, input.Height))
+	}
+	return framebuffers
+}
+
+func (f *Framebuffers) SetPixel(pixel *Pixel) {
+	f.Image.SetPixel(pixel)
+	for k, value := range pixel.AOVs {
+		f.AOVs[k].Pixels[pixel.J*f.Image.Width+pixel.I] = NewVectorFromArray(value)
+	}
+}
+
+// WriteFiles writes the image to outputFile and each pass next to it.
+func (f *Framebuffers) WriteFiles(input *ImageInput, outputFile string) error {
+	if err := writeFile(outputFile, f.Image, input, input.GetToneMapper()); err != nil {
+		return err
+	}
+	for k, name := range input.AOVs {
+		aovInput := *input
+		if IsIDAOV(name) {
+			// Half floats hold integers exactly only up to 2048.
+			aovInput.PixelType = FloatPixels
+		}
+
+		image, mapper := f.AOVs[k], (*ToneMapper)(nil)
+		if input.GetFormat() == PNGFormat {
+			image, mapper = input.AOVPreview(name, image)
+		}
+		if err := writeFile(AOVFileName(outputFile, name), image, &aovInput, mapper); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(fileName string, image *Image, input *ImageInput, mapper *ToneMapper) error {
+	file := utils.CreateNestedFile(fileName)
+	defer file.Close()
+	return input.writeImage(file, image, mapper)
+}
+
+// AOVPreview returns the pass image to quantise for PNG and how to tone map
+// it. Lighting passes are tone mapped like the image and colour passes only
+// encoded. IDs are shown as distinct colours and depth is scaled so the
+// farthest hit is white, while other data passes keep their values, clamped
+// to [0, 1].
+func (i *ImageInput) AOVPreview(name string, image *Image) (*Image, *ToneMapper) {
+	mapper := i.GetToneMapper()
+	switch name {
+	case DirectAOV, IndirectAOV:
+		return image, mapper
+	case AlbedoAOV:
+		return image, &ToneMapper{Operator: ClampToneMapping, Transfer: mapper.Transfer}
+	case ObjectIDAOV, MaterialIDAOV:
+		colors := NewFramebuffer(image.Width, image.Height)
+		for k, pixel := range image.Pixels {
+			colors.Pixels[k] = idColor(uint32(pixel.data[0]))
+		}
+		return colors, &ToneMapper{Operator: ClampToneMapping, Transfer: mapper.Transfer}
+	}
+
+	dataMapper := &ToneMapper{Operator: ClampToneMapping, Transfer: LinearTransfer}
+	if name == DepthAOV {
+		farthest := 0.0
+		for _, pixel := range image.Pixels {
+			farthest = math.Max(farthest, pixel.data[0])
+		}
+		if farthest > 0 {
+			dataMapper.Exposure = -math.Log2(farthest)
+		}
+	}
+	return image, dataMapper
+}
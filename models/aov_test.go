@@ -0,0 +1,82 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIDAOVWrittenExactly(t *testing.T) {
+	const id = 0xabcdef
+
+	directory, err := ioutil.TempDir("", "aov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	tests := []struct {
+		name string
+		file string
+		read func(t *testing.T, fileName string) float64
+	}{
+		{"PFM", "image.pfm", func(t *testing.T, fileName string) float64 {
+			image, err := LoadPFM(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return image.Pixels[0].data[0]
+		}},
+		{"EXR", "image.exr", func(t *testing.T, fileName string) float64 {
+			// Uncompressed scanlines hold the channels as plain floats.
+			data, err := ioutil.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value := make([]byte, 4)
+			binary.LittleEndian.PutUint32(value, math.Float32bits(id))
+			if !bytes.Contains(data, value) {
+				return 0
+			}
+			return id
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileName := filepath.Join(directory, test.file)
+			input := &ImageInput{
+				OutputFile:  fileName,
+				Width:       1,
+				Height:      1,
+				AOVs:        []string{ObjectIDAOV},
+				Compression: NoCompression,
+			}
+			input.Validate()
+
+			framebuffers := NewFramebuffers(input)
+			framebuffers.SetPixel(&Pixel{AOVs: [][3]float64{idValue(id).data}})
+
+			if err := framebuffers.WriteFiles(input, fileName); err != nil {
+				t.Fatal(err)
+			}
+			if got := test.read(t, AOVFileName(fileName, ObjectIDAOV)); got != id {
+				t.Errorf("got ID %v back, want %v", got, id)
+			}
+		})
+	}
+}
+
+func TestIDAOVRejectedForHDR(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Validate accepted an ID pass written as HDR")
+		}
+	}()
+	input := &ImageInput{OutputFile: "image.hdr", AOVs: []string{MaterialIDAOV}}
+	input.Validate()
+}
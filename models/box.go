@@ -0,0 +1,75 @@
+package models
+
+import "math"
+
+type Box struct {
+	Min, Max *Vector
+	Material Material
+}
+
+func NewBox(min, max *Vector, material Material) *Box {
+	return &Box{
+		Min:      min,
+		Max:      max,
+		Material: material,
+	}
+}
+
+func (b *Box) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	tNear, tFar := math.Inf(-1), math.Inf(1)
+	nearAxis, farAxis := 0, 0
+	for a := 0; a < 3; a++ {
+		invD := 1 / r.Direction.data[a]
+		t0 := (b.Min.data[a] - r.Origin.data[a]) * invD
+		t1 := (b.Max.data[a] - r.Origin.data[a]) * invD
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tNear {
+			tNear, nearAxis = t0, a
+		}
+		if t1 < tFar {
+			tFar, farAxis = t1, a
+		}
+		if tFar < tNear {
+			return false, nil
+		}
+	}
+
+	t, axis := tNear, nearAxis
+	if t <= tmin {
+		t, axis = tFar, farAxis
+	}
+	if t <= tmin || t >= tmax {
+		return false, nil
+	}
+
+	p := r.PointAtParameter(t)
+	n := NewEmptyVector()
+	if p.data[axis]-b.Min.data[axis] < b.Max.data[axis]-p.data[axis] {
+		n.data[axis] = -1
+	} else {
+		n.data[axis] = 1
+	}
+
+	a0, a1 := (axis+1)%3, (axis+2)%3
+	u := (p.data[a0] - b.Min.data[a0]) / (b.Max.data[a0] - b.Min.data[a0])
+	v := (p.data[a1] - b.Min.data[a1]) / (b.Max.data[a1] - b.Min.data[a1])
+	tangent, bitangent := NewEmptyVector(), NewEmptyVector()
+	tangent.data[a0], bitangent.data[a1] = 1, 1
+
+	return true, &HitRecord{
+		T:         t,
+		P:         p,
+		N:         n,
+		U:         u,
+		V:         v,
+		Tangent:   tangent,
+		Bitangent: bitangent,
+		Material:  b.Material,
+	}
+}
+
+func (b *Box) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return true, NewAABB(b.Min.Copy(), b.Max.Copy())
+}
@@ -0,0 +1,185 @@
+package models
+
+import "math"
+
+const (
+	bvhBuckets       = 12
+	bvhTraversalCost = 0.125
+)
+
+type BVHNode struct {
+	Left, Right Hitable
+	Box         *AABB
+}
+
+type bvhPrimitive struct {
+	hitable  Hitable
+	box      *AABB
+	centroid *Vector
+}
+
+func NewBVHNode(list []Hitable, t0, t1 float64) *BVHNode {
+	primitives := make([]bvhPrimitive, len(list))
+	for i, h := range list {
+		bounded, box := h.BoundingBox(t0, t1)
+		if !bounded {
+			panic("BVH can only be built from bounded hitables")
+		}
+		primitives[i] = bvhPrimitive{
+			hitable:  h,
+			box:      box,
+			centroid: box.Centroid(),
+		}
+	}
+
+	if len(primitives) == 1 {
+		return &BVHNode{
+			Left: primitives[0].hitable,
+			Box:  primitives[0].box,
+		}
+	}
+
+	return buildBVH(primitives).(*BVHNode)
+}
+
+func buildBVH(primitives []bvhPrimitive) Hitable {
+	if len(primitives) == 1 {
+		return primitives[0].hitable
+	}
+
+	box := primitives[0].box
+	centroidBox := NewAABB(primitives[0].centroid, primitives[0].centroid)
+	for _, p := range primitives[1:] {
+		box = SurroundingBox(box, p.box)
+		centroidBox = SurroundingBox(centroidBox, NewAABB(p.centroid, p.centroid))
+	}
+
+	mid := splitSAH(primitives, box, centroidBox)
+
+	return &BVHNode{
+		Left:  buildBVH(primitives[:mid]),
+		Right: buildBVH(primitives[mid:]),
+		Box:   box,
+	}
+}
+
+// splitSAH partitions primitives in place using a binned surface area
+// heuristic over the centroid extent and returns the index of the split.
+func splitSAH(primitives []bvhPrimitive, box, centroidBox *AABB) int {
+	n := len(primitives)
+	if n == 2 {
+		return 1
+	}
+
+	bestAxis, bestBucket := -1, 0
+	bestCost := math.MaxFloat64
+	parentArea := box.SurfaceArea()
+
+	for axis := 0; axis < 3; axis++ {
+		lo, hi := centroidBox.Min.data[axis], centroidBox.Max.data[axis]
+		if hi-lo <= 0 {
+			continue
+		}
+
+		var counts [bvhBuckets]int
+		var boxes [bvhBuckets]*AABB
+		for _, p := range primitives {
+			b := bvhBucket(p.centroid.data[axis], lo, hi)
+			counts[b]++
+			if boxes[b] == nil {
+				boxes[b] = p.box
+			} else {
+				boxes[b] = SurroundingBox(boxes[b], p.box)
+			}
+		}
+
+		var rightArea [bvhBuckets]float64
+		var rightCount [bvhBuckets]int
+		var acc *AABB
+		count := 0
+		for b := bvhBuckets - 1; b > 0; b-- {
+			if boxes[b] != nil {
+				acc = growBox(acc, boxes[b])
+				count += counts[b]
+			}
+			rightCount[b] = count
+			if acc != nil {
+				rightArea[b] = acc.SurfaceArea()
+			}
+		}
+
+		acc = nil
+		count = 0
+		for b := 0; b < bvhBuckets-1; b++ {
+			if boxes[b] != nil {
+				acc = growBox(acc, boxes[b])
+				count += counts[b]
+			}
+			if count == 0 || rightCount[b+1] == 0 {
+				continue
+			}
+			cost := bvhTraversalCost +
+				(acc.SurfaceArea()*float64(count)+rightArea[b+1]*float64(rightCount[b+1]))/parentArea
+			if cost < bestCost {
+				bestCost, bestAxis, bestBucket = cost, axis, b
+			}
+		}
+	}
+
+	if bestAxis < 0 {
+		return n / 2
+	}
+
+	lo, hi := centroidBox.Min.data[bestAxis], centroidBox.Max.data[bestAxis]
+	mid := 0
+	for i := range primitives {
+		if bvhBucket(primitives[i].centroid.data[bestAxis], lo, hi) <= bestBucket {
+			primitives[i], primitives[mid] = primitives[mid], primitives[i]
+			mid++
+		}
+	}
+
+	if mid == 0 || mid == n {
+		return n / 2
+	}
+	return mid
+}
+
+func bvhBucket(c, lo, hi float64) int {
+	b := int(bvhBuckets * (c - lo) / (hi - lo))
+	if b >= bvhBuckets {
+		b = bvhBuckets - 1
+	}
+	return b
+}
+
+func growBox(acc, box *AABB) *AABB {
+	if acc == nil {
+		return box
+	}
+	return SurroundingBox(acc, box)
+}
+
+func (n *BVHNode) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return true, n.Box
+}
+
+func (n *BVHNode) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	if !n.Box.Hit(r, tmin, tmax) {
+		return false, nil
+	}
+
+	hitLeft, record := n.Left.Hit(r, tmin, tmax)
+	if hitLeft {
+		tmax = record.T
+	}
+
+	if n.Right != nil {
+		hitRight, rightRecord := n.Right.Hit(r, tmin, tmax)
+		if hitRight {
+			return true, rightRecord
+		}
+	}
+
+	return hitLeft, record
+}
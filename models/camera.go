@@ -6,10 +6,11 @@ import (
 )
 
 type Camera struct {
-	LowerLeftCorner, Origin *Vector
-	Horizontal, Vertical    *Vector
-	LensRadius              float64
-	U, V, W                 *Vector
+	LowerLeftCorner, Origin   *Vector
+	Horizontal, Vertical      *Vector
+	LensRadius                float64
+	U, V, W                   *Vector
+	ShutterOpen, ShutterClose float64
 }
 
 func (c *Camera) RayAt(u, v float64, rng *rand.Rand) *Ray {
@@ -24,9 +25,16 @@ func (c *Camera) RayAt(u, v float64, rng *rand.Rand) *Ray {
 		AddScaledVector(c.Vertical, v).
 		SubtractVector(origin)
 
+	time := c.ShutterOpen
+	if c.ShutterClose > c.ShutterOpen {
+		time += rng.Float64() * (c.ShutterClose - c.ShutterOpen)
+	}
+
 	return &Ray{
 		origin,
 		compositeDir,
+		time,
+		rng,
 	}
 }
 
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"math"
+)
+
+const (
+	CSGUnion        = "Union"
+	CSGIntersection = "Intersection"
+	CSGDifference   = "Difference"
+
+	csgEpsilon      = 1e-7
+	csgMaxCrossings = 64
+)
+
+// CSG combines two closed hitables with a boolean operation. Both operands
+// must have outward facing normals so that each surface crossing along a ray
+// can be classified as entering or leaving the solid.
+type CSG struct {
+	Operation   string
+	Left, Right Hitable
+
+	// The box over the shutter interval is kept to reject rays cheaply.
+	bounded bool
+	box     *AABB
+}
+
+func NewCSG(operation string, left, right Hitable, t0, t1 float64) *CSG {
+	csg := &CSG{
+		Operation: operation,
+		Left:      left,
+		Right:     right,
+	}
+	csg.bounded, csg.box = csg.BoundingBox(t0, t1)
+	return csg
+}
+
+type csgCrossing struct {
+	record   *HitRecord
+	entering bool
+}
+
+// csgCrossings returns every surface crossing of h along the whole line of
+// the ray, in order. Nested CSG nodes hand over their merged crossings, so
+// each operand surface is walked only once per ray however deep the tree.
+func csgCrossings(h Hitable, r *Ray) []csgCrossing {
+	if c, ok := h.(*CSG); ok {
+		return c.crossings(r)
+	}
+
+	var crossings []csgCrossing
+	t := -math.MaxFloat64
+	for i := 0; i < csgMaxCrossings; i++ {
+		hit, record := h.Hit(r, t, math.MaxFloat64)
+		if !hit {
+			break
+		}
+		crossings = append(crossings, csgCrossing{
+			record:   record,
+			entering: record.N.Dot(r.Direction) < 0,
+		})
+		t = record.T + csgEpsilon*math.Max(1, math.Abs(record.T))
+	}
+	return crossings
+}
+
+func (c *CSG) inside(inLeft, inRight bool) bool {
+	switch c.Operation {
+	case CSGIntersection:
+		return inLeft && inRight
+	case CSGDifference:
+		return inLeft && !inRight
+	default:
+		return inLeft || inRight
+	}
+}
+
+// crossings merges the crossings of both operands in one pass and keeps
+// those where the combined solid is entered or left.
+func (c *CSG) crossings(r *Ray) []csgCrossing {
+	left := csgCrossings(c.Left, r)
+	if len(left) == 0 && c.Operation != CSGUnion {
+		return nil
+	}
+	right := csgCrossings(c.Right, r)
+	if len(right) == 0 && c.Operation == CSGIntersection {
+		return nil
+	}
+
+	// A solid whose first crossing is an exit started out containing the
+	// beginning of the line.
+	inLeft := len(left) > 0 && !left[0].entering
+	inRight := len(right) > 0 && !right[0].entering
+
+	var crossings []csgCrossing
+	for len(left) > 0 || len(right) > 0 {
+		wasInside := c.inside(inLeft, inRight)
+
+		var crossing csgCrossing
+		isLeft := len(right) == 0 || (len(left) > 0 && left[0].record.T <= right[0].record.T)
+		if isLeft {
+			crossing, left = left[0], left[1:]
+			inLeft = crossing.entering
+		} else {
+			crossing, right = right[0], right[1:]
+			inRight = crossing.entering
+		}
+
+		isInside := c.inside(inLeft, inRight)
+		if wasInside == isInside {
+			continue
+		}
+
+		record := crossing.record
+		if c.Operation == CSGDifference && !isLeft {
+			record.N = record.N.Copy().Negate()
+		}
+		crossings = append(crossings, csgCrossing{record: record, entering: isInside})
+	}
+	return crossings
+}
+
+func (c *CSG) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	if c.bounded && !c.box.Hit(r, tmin, tmax) {
+		return false, nil
+	}
+
+	for _, crossing := range c.crossings(r) {
+		t := crossing.record.T
+		if t >= tmax {
+			break
+		}
+		if t > tmin {
+			return true, crossing.record
+		}
+	}
+	return false, nil
+}
+
+func (c *CSG) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	leftBounded, leftBox := c.Left.BoundingBox(t0, t1)
+	rightBounded, rightBox := c.Right.BoundingBox(t0, t1)
+
+	switch c.Operation {
+	case CSGIntersection:
+		if leftBounded && rightBounded {
+			return true, intersectBoxes(leftBox, rightBox)
+		} else if leftBounded {
+			return true, leftBox
+		}
+		return rightBounded, rightBox
+	case CSGDifference:
+		return leftBounded, leftBox
+	default:
+		if leftBounded && rightBounded {
+			return true, SurroundingBox(leftBox, rightBox)
+		}
+		return false, nil
+	}
+}
+
+func intersectBoxes(b0, b1 *AABB) *AABB {
+	box := NewAABB(
+		NewVector(
+			math.Max(b0.Min.data[0], b1.Min.data[0]),
+			math.Max(b0.Min.data[1], b1.Min.data[1]),
+			math.Max(b0.Min.data[2], b1.Min.data[2]),
+		),
+		NewVector(
+			math.Min(b0.Max.data[0], b1.Max.data[0]),
+			math.Min(b0.Max.data[1], b1.Max.data[1]),
+			math.Min(b0.Max.data[2], b1.Max.data[2]),
+		),
+	)
+	for a := 0; a < 3; a++ {
+		if box.Max.data[a] < box.Min.data[a] {
+			box.Max.data[a] = box.Min.data[a]
+		}
+	}
+	return box
+}
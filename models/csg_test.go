@@ -0,0 +1,97 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+type countingHitable struct {
+	Hitable
+	calls int
+}
+
+func (c *countingHitable) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	c.calls++
+	return c.Hitable.Hit(r, tmin, tmax)
+}
+
+func TestCSGHit(t *testing.T) {
+	// Unit spheres centred at x = -0.5 and x = 0.5 overlap between x = -0.5
+	// and x = 0.5. The ray runs along +X from x = -5.
+	a := NewSphere(-0.5, 0, 0, 1, nil)
+	b := NewSphere(0.5, 0, 0, 1, nil)
+
+	tests := []struct {
+		name        string
+		operation   string
+		left, right Hitable
+		origin      float64
+		want        []float64
+		wantNormalX []float64
+	}{
+		{"union", CSGUnion, a, b, -5, []float64{3.5, 6.5}, []float64{-1, 1}},
+		{"intersection", CSGIntersection, a, b, -5, []float64{4.5, 5.5}, []float64{-1, 1}},
+		{"difference", CSGDifference, a, b, -5, []float64{3.5, 4.5}, []float64{-1, 1}},
+		{"reversed difference", CSGDifference, b, a, -5, []float64{5.5, 6.5}, []float64{-1, 1}},
+		{"union from inside", CSGUnion, a, b, 0, []float64{1.5}, []float64{1}},
+		{"intersection of disjoint", CSGIntersection, a, NewSphere(5, 0, 0, 1, nil), -5, nil, nil},
+		{"difference of empty left", CSGDifference, NewSphere(0, 5, 0, 1, nil), b, -5, nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			csg := NewCSG(test.operation, test.left, test.right, 0, 0)
+			r := &Ray{Origin: NewVector(test.origin, 0, 0), Direction: NewVector(1, 0, 0)}
+
+			var got, gotNormalX []float64
+			tmin := 0.0
+			for {
+				hit, record := csg.Hit(r, tmin, math.MaxFloat64)
+				if !hit {
+					break
+				}
+				got = append(got, record.T)
+				gotNormalX = append(gotNormalX, record.N.X())
+				tmin = record.T + 1e-6
+			}
+
+			if len(got) != len(test.want) {
+				t.Fatalf("got hits at %v, want %v", got, test.want)
+			}
+			for k := range got {
+				if math.Abs(got[k]-test.want[k]) > 1e-9 || math.Abs(gotNormalX[k]-test.wantNormalX[k]) > 1e-9 {
+					t.Errorf("hit %d at t=%v with normal x %v, want t=%v with normal x %v",
+						k, got[k], gotNormalX[k], test.want[k], test.wantNormalX[k])
+				}
+			}
+		})
+	}
+}
+
+func TestCSGNestedHitCost(t *testing.T) {
+	// A chain of unions, as built for multi-object operands, must walk each
+	// operand once per ray rather than once per level.
+	var spheres []*countingHitable
+	var operand Hitable
+	for k := 0; k < 8; k++ {
+		sphere := &countingHitable{Hitable: NewSphere(float64(k)*0.5, 0, 0, 0.4, nil)}
+		spheres = append(spheres, sphere)
+		if operand == nil {
+			operand = sphere
+		} else {
+			operand = NewCSG(CSGUnion, operand, sphere, 0, 0)
+		}
+	}
+
+	r := &Ray{Origin: NewVector(-5, 0, 0), Direction: NewVector(1, 0, 0)}
+	hit, record := operand.Hit(r, 0, math.MaxFloat64)
+	if !hit || math.Abs(record.T-4.6) > 1e-9 {
+		t.Fatalf("got hit %t at %v, want hit at 4.6", hit, record)
+	}
+	for k, sphere := range spheres {
+		// Two crossings and a final miss.
+		if sphere.calls != 3 {
+			t.Errorf("sphere %d hit %d times, want 3", k, sphere.calls)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"math"
+
+	"github.com/DheerendraRathor/GoTracer/utils"
+)
+
+// Cylinder runs from the center of its base along Axis for Height. In its
+// local frame the base is centered at the origin and the axis is +Y.
+type Cylinder struct {
+	Frame    *Frame
+	Radius   float64
+	Height   float64
+	Capped   bool
+	Material Material
+}
+
+func NewCylinder(base, top *Vector, radius float64, capped bool, material Material) *Cylinder {
+	axis := top.Copy().SubtractVector(base)
+	return &Cylinder{
+		Frame:    NewFrame(base, axis),
+		Radius:   radius,
+		Height:   axis.Length(),
+		Capped:   capped,
+		Material: material,
+	}
+}
+
+func (c *Cylinder) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	local := c.Frame.ToLocalRay(r)
+	o, d := local.Origin.data, local.Direction.data
+
+	var record *HitRecord
+	a := d[0]*d[0] + d[2]*d[2]
+	if a > 0 {
+		b := 2 * (o[0]*d[0] + o[2]*d[2])
+		cc := o[0]*o[0] + o[2]*o[2] - c.Radius*c.Radius
+		for _, t := range utils.SolveQuadratic(a, b, cc) {
+			if t <= tmin || t >= tmax {
+				continue
+			}
+			y := o[1] + t*d[1]
+			if y < 0 || y > c.Height {
+				continue
+			}
+			x, z := o[0]+t*d[0], o[2]+t*d[2]
+			tmax = t
+			record = &HitRecord{
+				T:         t,
+				N:         NewVector(x/c.Radius, 0, z/c.Radius),
+				U:         azimuthU(x, z),
+				V:         y / c.Height,
+				Tangent:   NewVector(-z, 0, x),
+				Bitangent: NewVector(0, 1, 0),
+			}
+		}
+	}
+
+	if c.Capped {
+		if capRecord := hitLocalCap(local, 0, -1, c.Radius, tmin, tmax); capRecord != nil {
+			tmax = capRecord.T
+			record = capRecord
+		}
+		if capRecord := hitLocalCap(local, c.Height, 1, c.Radius, tmin, tmax); capRecord != nil {
+			record = capRecord
+		}
+	}
+
+	if record == nil {
+		return false, nil
+	}
+
+	record.P = r.PointAtParameter(record.T)
+	record.N = c.Frame.ToWorldDirection(record.N)
+	record.Tangent = c.Frame.ToWorldDirection(record.Tangent)
+	record.Bitangent = c.Frame.ToWorldDirection(record.Bitangent)
+	record.Material = c.Material
+	return true, record
+}
+
+func (c *Cylinder) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	top := c.Frame.Origin.Copy().AddScaledVector(c.Frame.Y, c.Height)
+	return true, SurroundingBox(
+		diskBoundingBox(c.Frame.Origin, c.Frame.Y, c.Radius),
+		diskBoundingBox(top, c.Frame.Y, c.Radius),
+	)
+}
+
+// Cone has a circular base of Radius and narrows to its apex at Height along
+// the local +Y axis.
+type Cone struct {
+	Frame    *Frame
+	Radius   float64
+	Height   float64
+	Capped   bool
+	Material Material
+}
+
+func NewCone(base, apex *Vector, radius float64, capped bool, material Material) *Cone {
+	axis := apex.Copy().SubtractVector(base)
+	return &Cone{
+		Frame:    NewFrame(base, axis),
+		Radius:   radius,
+		Height:   axis.Length(),
+		Capped:   capped,
+		Material: material,
+	}
+}
+
+func (c *Cone) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	local := c.Frame.ToLocalRay(r)
+	o, d := local.Origin.data, local.Direction.data
+
+	// x^2 + z^2 = (radius - k*y)^2 where k is the slope of the side
+	k := c.Radius / c.Height
+	ry := c.Radius - k*o[1]
+	a := d[0]*d[0] + d[2]*d[2] - k*k*d[1]*d[1]
+	b := 2 * (o[0]*d[0] + o[2]*d[2] + k*d[1]*ry)
+	cc := o[0]*o[0] + o[2]*o[2] - ry*ry
+
+	var record *HitRecord
+	if a != 0 {
+		for _, t := range utils.SolveQuadratic(a, b, cc) {
+			if t <= tmin || t >= tmax {
+				continue
+			}
+			y := o[1] + t*d[1]
+			if y < 0 || y > c.Height {
+				continue
+			}
+			x, z := o[0]+t*d[0], o[2]+t*d[2]
+			tmax = t
+			n := NewVector(x, k*(c.Radius-k*y), z).MakeUnitVector()
+			tangent := NewVector(-z, 0, x)
+			record = &HitRecord{
+				T:         t,
+				N:         n,
+				U:         azimuthU(x, z),
+				V:         y / c.Height,
+				Tangent:   tangent,
+				Bitangent: NewEmptyVector().VectorCrossProduct(tangent, n),
+			}
+		}
+	}
+
+	if c.Capped {
+		if capRecord := hitLocalCap(local, 0, -1, c.Radius, tmin, tmax); capRecord != nil {
+			record = capRecord
+		}
+	}
+
+	if record == nil {
+		return false, nil
+	}
+
+	record.P = r.PointAtParameter(record.T)
+	record.N = c.Frame.ToWorldDirection(record.N)
+	record.Tangent = c.Frame.ToWorldDirection(record.Tangent)
+	record.Bitangent = c.Frame.ToWorldDirection(record.Bitangent)
+	record.Material = c.Material
+	return true, record
+}
+
+func (c *Cone) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	apex := c.Frame.Origin.Copy().AddScaledVector(c.Frame.Y, c.Height)
+	return true, SurroundingBox(
+		diskBoundingBox(c.Frame.Origin, c.Frame.Y, c.Radius),
+		NewAABB(apex, apex.Copy()),
+	)
+}
+
+// hitLocalCap intersects a local ray with the disk of given radius lying in
+// the plane y = height and facing ny along Y. The returned record is in
+// local space.
+func hitLocalCap(local *Ray, height, ny, radius float64, tmin, tmax float64) *HitRecord {
+	o, d := local.Origin.data, local.Direction.data
+	if d[1] == 0 {
+		return nil
+	}
+	t := (height - o[1]) / d[1]
+	if t <= tmin || t >= tmax {
+		return nil
+	}
+	x, z := o[0]+t*d[0], o[2]+t*d[2]
+	if x*x+z*z > radius*radius {
+		return nil
+	}
+	return &HitRecord{
+		T:         t,
+		N:         NewVector(0, ny, 0),
+		U:         0.5 + x/(2*radius),
+		V:         0.5 + z/(2*radius),
+		Tangent:   NewVector(1, 0, 0),
+		Bitangent: NewVector(0, 0, 1),
+	}
+}
+
+func azimuthU(x, z float64) float64 {
+	return (math.Atan2(z, x) + math.Pi) / (2 * math.Pi)
+}
+
+func diskBoundingBox(center, normal *Vector, radius float64) *AABB {
+	extent := NewEmptyVector()
+	for a := 0; a < 3; a++ {
+		extent.data[a] = radius * math.Sqrt(math.Max(0, 1-normal.data[a]*normal.data[a]))
+	}
+	return NewAABB(
+		center.Copy().SubtractVector(extent),
+		center.Copy().AddVector(extent),
+	).Pad(planeBoxPadding)
+}
@@ -0,0 +1,89 @@
+package models
+
+import "math"
+
+const (
+	PointLight       = "Point"
+	SpotLight        = "Spot"
+	DirectionalLight = "Directional"
+)
+
+// DeltaLight is a light without geometry, reaching each point from a single
+// direction. Rays never hit it, so it is only found by shadow rays.
+type DeltaLight interface {
+	// Illuminate returns the unit direction from point towards the light,
+	// the distance to it and the radiance arriving at point, ignoring
+	// anything in between.
+	Illuminate(point *Vector) (*Vector, float64, *Vector)
+}
+
+// Point shines Intensity equally in all directions from Position, falling off
+// with the square of the distance.
+type Point struct {
+	Position  *Vector
+	Intensity *Vector
+}
+
+func NewPoint(position, intensity *Vector) *Point {
+	return &Point{
+		Position:  position,
+		Intensity: intensity,
+	}
+}
+
+func (p *Point) Illuminate(point *Vector) (*Vector, float64, *Vector) {
+	toLight := p.Position.Copy().SubtractVector(point)
+	distance2 := toLight.SquaredLength()
+	return toLight.MakeUnitVector(), math.Sqrt(distance2), p.Intensity.Copy().Scale(1 / distance2)
+}
+
+// Spot is a point light shining along Direction. It has full intensity
+// within the inner cone and fades to nothing at the outer cone, with Falloff
+// shaping the fade.
+type Spot struct {
+	*Point
+	Direction          *Vector
+	CosInner, CosOuter float64
+	Falloff            float64
+}
+
+// NewSpot takes the half angles of its cones in degrees.
+func NewSpot(position, direction, intensity *Vector, innerAngle, outerAngle, falloff float64) *Spot {
+	return &Spot{
+		Point:     NewPoint(position, intensity),
+		Direction: direction.Copy().MakeUnitVector(),
+		CosInner:  math.Cos(innerAngle * math.Pi / 180),
+		CosOuter:  math.Cos(outerAngle * math.Pi / 180),
+		Falloff:   falloff,
+	}
+}
+
+func (s *Spot) Illuminate(point *Vector) (*Vector, float64, *Vector) {
+	direction, distance, radiance := s.Point.Illuminate(point)
+	cos := -direction.Dot(s.Direction)
+	switch {
+	case cos <= s.CosOuter:
+		radiance = NewEmptyVector()
+	case cos < s.CosInner:
+		radiance.Scale(math.Pow((cos-s.CosOuter)/(s.CosInner-s.CosOuter), s.Falloff))
+	}
+	return direction, distance, radiance
+}
+
+// Directional lights the scene with Radiance travelling along Direction, as
+// from a light infinitely far away like the sun.
+type Directional struct {
+	Direction *Vector
+	Radiance  *Vector
+}
+
+func NewDirectional(direction, radiance *Vector) *Directional {
+	return &Directional{
+		Direction: direction.Copy().MakeUnitVector(),
+		Radiance:  radiance,
+	}
+}
+
+func (d *Directional) Illuminate(point *Vector) (*Vector, float64, *Vector) {
+	return d.Direction.Copy().Negate(), math.Inf(1), d.Radiance.Copy()
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadEnvironmentImage reads a Radiance .hdr or a PFM file by its extension.
+func LoadEnvironmentImage(filePath string) (*Image, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".hdr", ".pic":
+		return LoadHDR(filePath)
+	case ".pfm":
+		return LoadPFM(filePath)
+	}
+	return nil, fmt.Errorf("%s: unsupported environment map format", filePath)
+}
+
+// LoadHDR reads a Radiance RGBE picture, either flat or run length encoded,
+// stored top to bottom.
+func LoadHDR(filePath string) (*Image, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	image, err := readHDR(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+	return image, nil
+}
+
+func readHDR(reader *bufio.Reader) (*Image, error) {
+	magic, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(magic, "#?") {
+		return nil, fmt.Errorf("not a Radiance picture")
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported %s", line)
+		}
+	}
+
+	var width, height int
+	resolution, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("unsupported resolution %q", strings.TrimSpace(resolution))
+	}
+
+	image := &Image{
+		Width:  width,
+		Height: height,
+		Pixels: make([]*Vector, width*height),
+	}
+	scanline := make([]byte, 4*width)
+	for j := 0; j < height; j++ {
+		if err := readHDRScanline(reader, scanline, width); err != nil {
+			return nil, err
+		}
+		for i := 0; i < width; i++ {
+			image.Pixels[j*width+i] = rgbeToVector(scanline[4*i : 4*i+4])
+		}
+	}
+	return image, nil
+}
+
+// readHDRScanline reads one row of RGBE pixels into scanline, which holds
+// them interleaved.
+func readHDRScanline(reader *bufio.Reader, scanline []byte, width int) error {
+	header, err := reader.Peek(4)
+	if err != nil {
+		return err
+	}
+	if width < 8 || width > 0x7fff || header[0] != 2 || header[1] != 2 || header[2]&0x80 != 0 {
+		_, err := io.ReadFull(reader, scanline)
+		return err
+	}
+	if int(header[2])<<8|int(header[3]) != width {
+		return fmt.Errorf("scanline width mismatch")
+	}
+	reader.Discard(4)
+
+	// Each channel is run length encoded separately.
+	for c := 0; c < 4; c++ {
+		for i := 0; i < width; {
+			count, err := reader.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				run := int(count) - 128
+				value, err := reader.ReadByte()
+				if err != nil {
+					return err
+				}
+				if i+run > width {
+					return fmt.Errorf("bad scanline data")
+				}
+				for ; run > 0; run-- {
+					scanline[4*i+c] = value
+					i++
+				}
+			} else {
+				if count == 0 || i+int(count) > width {
+					return fmt.Errorf("bad scanline data")
+				}
+				for ; count > 0; count-- {
+					value, err := reader.ReadByte()
+					if err != nil {
+						return err
+					}
+					scanline[4*i+c] = value
+					i++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func rgbeToVector(rgbe []byte) *Vector {
+	if rgbe[3] == 0 {
+		return NewEmptyVector()
+	}
+	f := math.Ldexp(1, int(rgbe[3])-(128+8))
+	return NewVector(float64(rgbe[0])+0.5, float64(rgbe[1])+0.5, float64(rgbe[2])+0.5).Scale(f)
+}
+
+// LoadPFM reads a colour (PF) or grayscale (Pf) portable float map. Its rows
+// run bottom to top and a negative scale marks little endian data.
+func LoadPFM(filePath string) (*Image, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var kind string
+	var width, height int
+	var scale float64
+	if _, err := fmt.Fscan(reader, &kind, &width, &height, &scale); err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+	if _, err := reader.ReadByte(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	channels := 3
+	switch kind {
+	case "PF":
+	case "Pf":
+		channels = 1
+	default:
+		return nil, fmt.Errorf("%s: not a portable float map", filePath)
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+
+	data := make([]float32, width*height*channels)
+	if err := binary.Read(reader, order, data); err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	image := &Image{
+		Width:  width,
+		Height: height,
+		Pixels: make([]*Vector, width*height),
+	}
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			k := (j*width + i) * channels
+			color := NewVector(float64(data[k]), float64(data[k]), float64(data[k]))
+			if channels == 3 {
+				color = NewVector(float64(data[k]), float64(data[k+1]), float64(data[k+2]))
+			}
+			image.Pixels[(height-1-j)*width+i] = color
+		}
+	}
+	return image, nil
+}
+
+// luminance is the brightness of a linear RGB colour.
+func luminance(color *Vector) float64 {
+	return 0.2126*color.data[0] + 0.7152*color.data[1] + 0.0722*color.data[2]
+}
+
+// Environment surrounds the scene with an equirectangular map, lighting
+// everything rays escape to. The centre of the map lies along -Z before the
+// map is turned by Rotation degrees about the Y axis. Directions are sampled
+// by the luminance of the map's pixels.
+type Environment struct {
+	Map       *Image
+	Rotation  float64
+	Intensity float64
+
+	sin, cos    float64
+	rows        []float64
+	columns     [][]float64
+	totalWeight float64
+}
+
+func NewEnvironment(environmentMap *Image, rotation, intensity float64) *Environment {
+	e := &Environment{
+		Map:       environmentMap,
+		Rotation:  rotation,
+		Intensity: intensity,
+		sin:       math.Sin(rotation * math.Pi / 180),
+		cos:       math.Cos(rotation * math.Pi / 180),
+		rows:      make([]float64, environmentMap.Height+1),
+		columns:   make([][]float64, environmentMap.Height),
+	}
+
+	// Cumulative weights of the pixels in each row and of the rows, with
+	// rows shrunk by the area they cover near the poles.
+	width := environmentMap.Width
+	for j := range e.columns {
+		sinTheta := math.Sin(math.Pi * (float64(j) + 0.5) / float64(environmentMap.Height))
+		columns := make([]float64, width+1)
+		for i := 0; i < width; i++ {
+			columns[i+1] = columns[i] + luminance(environmentMap.Pixels[j*width+i])*sinTheta
+		}
+		e.columns[j] = columns
+		e.rows[j+1] = e.rows[j] + columns[width]
+	}
+	e.totalWeight = e.rows[environmentMap.Height]
+	return e
+}
+
+// CanSample reports whether the map has any light to sample.
+func (e *Environment) CanSample() bool {
+	return e.totalWeight > 0
+}
+
+// pixel returns the column and row of the map seen along direction, and the
+// sine of the direction's angle from the Y axis.
+func (e *Environment) pixel(direction *Vector) (int, int, float64) {
+	d := direction.Copy().MakeUnitVector()
+	x := d.data[0]*e.cos - d.data[2]*e.sin
+	z := d.data[0]*e.sin + d.data[2]*e.cos
+	theta := math.Acos(math.Max(-1, math.Min(1, d.data[1])))
+	u := 0.5 + math.Atan2(x, -z)/(2*math.Pi)
+	v := theta / math.Pi
+
+	i := minInt(maxInt(int(u*float64(e.Map.Width)), 0), e.Map.Width-1)
+	j := minInt(maxInt(int(v*float64(e.Map.Height)), 0), e.Map.Height-1)
+	return i, j, math.Sin(theta)
+}
+
+// Radiance returns the light arriving along the reverse of direction.
+func (e *Environment) Radiance(direction *Vector) *Vector {
+	i, j, _ := e.pixel(direction)
+	return e.Map.Pixels[j*e.Map.Width+i].Copy().Scale(e.Intensity)
+}
+
+// SampleDirection picks a pixel by its weight and a direction uniformly
+// within it.
+func (e *Environment) SampleDirection(rng *rand.Rand) *Vector {
+	j := searchCumulative(e.rows, rng.Float64()*e.totalWeight)
+	i := searchCumulative(e.columns[j], rng.Float64()*e.columns[j][e.Map.Width])
+	u := (float64(i) + rng.Float64()) / float64(e.Map.Width)
+	v := (float64(j) + rng.Float64()) / float64(e.Map.Height)
+	return e.direction(u, v)
+}
+
+// direction returns the unit direction seen at (u, v) of the map, with v
+// running down from the top.
+func (e *Environment) direction(u, v float64) *Vector {
+	phi, theta := 2*math.Pi*(u-0.5), math.Pi*v
+	x, y, z := math.Sin(theta)*math.Sin(phi), math.Cos(theta), -math.Sin(theta)*math.Cos(phi)
+	return NewVector(x*e.cos+z*e.sin, y, -x*e.sin+z*e.cos)
+}
+
+// DirectionPDF is the solid angle density of SampleDirection picking
+// direction.
+func (e *Environment) DirectionPDF(direction *Vector) float64 {
+	i, j, sinTheta := e.pixel(direction)
+	if sinTheta <= 0 {
+		return 0
+	}
+	weight := e.columns[j][i+1] - e.columns[j][i]
+	pixels := float64(e.Map.Width * e.Map.Height)
+	return weight / e.totalWeight * pixels / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+// searchCumulative returns the index of the interval of cumulative weights
+// holding value, skipping empty intervals.
+func searchCumulative(cumulative []float64, value float64) int {
+	n := len(cumulative) - 1
+	i := sort.Search(n, func(k int) bool { return cumulative[k+1] > value })
+	return minInt(i, n-1)
+}
@@ -0,0 +1,46 @@
+package models
+
+// Frame is an orthonormal coordinate system. Shapes with an axis of symmetry
+// are intersected in a frame whose Y axis is that axis.
+type Frame struct {
+	Origin  *Vector
+	X, Y, Z *Vector
+}
+
+func NewFrame(origin, axis *Vector) *Frame {
+	y := axis.Copy().MakeUnitVector()
+	z, x := OrthonormalBasis(y)
+	return &Frame{
+		Origin: origin,
+		X:      x,
+		Y:      y,
+		Z:      z,
+	}
+}
+
+func (f *Frame) ToLocalDirection(v *Vector) *Vector {
+	return NewVector(v.Dot(f.X), v.Dot(f.Y), v.Dot(f.Z))
+}
+
+func (f *Frame) ToLocalPoint(p *Vector) *Vector {
+	return f.ToLocalDirection(p.Copy().SubtractVector(f.Origin))
+}
+
+func (f *Frame) ToWorldDirection(v *Vector) *Vector {
+	return f.X.Copy().Scale(v.data[0]).
+		AddScaledVector(f.Y, v.data[1]).
+		AddScaledVector(f.Z, v.data[2])
+}
+
+func (f *Frame) ToWorldPoint(p *Vector) *Vector {
+	return f.ToWorldDirection(p).AddVector(f.Origin)
+}
+
+func (f *Frame) ToLocalRay(r *Ray) *Ray {
+	return &Ray{
+		Origin:    f.ToLocalPoint(r.Origin),
+		Direction: f.ToLocalDirection(r.Direction),
+		Time:      r.Time,
+		Rand:      r.Rand,
+	}
+}
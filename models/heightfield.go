@@ -0,0 +1,206 @@
+package models
+
+import (
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+)
+
+// LoadHeightmap reads a PNG image as a grid of heights between 0 and 1, row
+// by row from the top of the image. Colour images are converted to gray.
+func LoadHeightmap(filePath string) ([]float64, int, int, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	bounds := img.Bounds()
+	columns, rows := bounds.Dx(), bounds.Dy()
+	if columns < 2 || rows < 2 {
+		return nil, 0, 0, fmt.Errorf("%s: heightmap must be at least 2x2 pixels", filePath)
+	}
+
+	heights := make([]float64, columns*rows)
+	for j := 0; j < rows; j++ {
+		for i := 0; i < columns; i++ {
+			gray := color.Gray16Model.Convert(img.At(bounds.Min.X+i, bounds.Min.Y+j)).(color.Gray16)
+			heights[j*columns+i] = float64(gray.Y) / math.MaxUint16
+		}
+	}
+	return heights, columns, rows, nil
+}
+
+// Heightfield is a terrain surface over a regular grid of heights. Column i
+// and row j of the grid sit at Min + (i*Size.X/(columns-1), h*Size.Y,
+// j*Size.Z/(rows-1)) where h is the grid height between 0 and 1. Each grid
+// cell is split into two triangles with normals interpolated between the
+// vertices.
+type Heightfield struct {
+	Heights       []float64
+	Normals       []*Vector
+	Columns, Rows int
+	Min, Size     *Vector
+	Material      Material
+	box           *AABB
+}
+
+func NewHeightfield(heights []float64, columns, rows int, min, size *Vector, material Material) *Heightfield {
+	h := &Heightfield{
+		Heights:  heights,
+		Columns:  columns,
+		Rows:     rows,
+		Min:      min,
+		Size:     size,
+		Material: material,
+	}
+
+	low, high := math.MaxFloat64, -math.MaxFloat64
+	for _, height := range heights {
+		low = math.Min(low, height)
+		high = math.Max(high, height)
+	}
+	h.box = NewAABB(
+		NewVector(min.data[0], min.data[1]+low*size.data[1], min.data[2]),
+		NewVector(min.data[0]+size.data[0], min.data[1]+high*size.data[1], min.data[2]+size.data[2]),
+	).Pad(planeBoxPadding)
+
+	h.Normals = make([]*Vector, columns*rows)
+	for j := 0; j < rows; j++ {
+		for i := 0; i < columns; i++ {
+			h.Normals[j*columns+i] = h.vertexNormal(i, j)
+		}
+	}
+	return h
+}
+
+func (h *Heightfield) cellSize() (float64, float64) {
+	return h.Size.data[0] / float64(h.Columns-1), h.Size.data[2] / float64(h.Rows-1)
+}
+
+func (h *Heightfield) vertex(i, j int) *Vector {
+	dx, dz := h.cellSize()
+	return NewVector(
+		h.Min.data[0]+float64(i)*dx,
+		h.Min.data[1]+h.Heights[j*h.Columns+i]*h.Size.data[1],
+		h.Min.data[2]+float64(j)*dz,
+	)
+}
+
+// vertexNormal uses central differences of the heights, falling back to one
+// sided differences along the edges of the grid.
+func (h *Heightfield) vertexNormal(i, j int) *Vector {
+	dx, dz := h.cellSize()
+	i0, i1 := maxInt(i-1, 0), minInt(i+1, h.Columns-1)
+	j0, j1 := maxInt(j-1, 0), minInt(j+1, h.Rows-1)
+
+	slopeX := (h.Heights[j*h.Columns+i1] - h.Heights[j*h.Columns+i0]) * h.Size.data[1] / (float64(i1-i0) * dx)
+	slopeZ := (h.Heights[j1*h.Columns+i] - h.Heights[j0*h.Columns+i]) * h.Size.data[1] / (float64(j1-j0) * dz)
+	return NewVector(-slopeX, 1, -slopeZ).MakeUnitVector()
+}
+
+// cellHit returns the nearer hit of the two triangles of the cell whose
+// lowest corner is at column i and row j. A ray can hit both when the cell
+// isn't planar.
+func (h *Heightfield) cellHit(r *Ray, i, j int, tmin, tmax float64) (bool, *HitRecord) {
+	var closest *HitRecord
+	corners := [4][2]int{{i, j}, {i, j + 1}, {i + 1, j}, {i + 1, j + 1}}
+	for _, indices := range [2][3]int{{0, 1, 2}, {2, 1, 3}} {
+		triangle := &Triangle{Material: h.Material, HasUV: true}
+		for c, index := range indices {
+			ci, cj := corners[index][0], corners[index][1]
+			triangle.Vertices[c] = h.vertex(ci, cj)
+			triangle.Normals[c] = h.Normals[cj*h.Columns+ci]
+			triangle.UVs[c] = [2]float64{
+				float64(ci) / float64(h.Columns-1),
+				1 - float64(cj)/float64(h.Rows-1),
+			}
+		}
+		if hit, record := triangle.Hit(r, tmin, tmax); hit {
+			closest, tmax = record, record.T
+		}
+	}
+	return closest != nil, closest
+}
+
+// Hit walks the grid cells under the ray in order with a 2D DDA over the XZ
+// plane, so the first cell with a hit holds the nearest one.
+func (h *Heightfield) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	hit, t0, t1 := h.box.Clip(r, tmin, tmax)
+	if !hit {
+		return false, nil
+	}
+
+	dx, dz := h.cellSize()
+	start := r.PointAtParameter(t0)
+	i := minInt(maxInt(int((start.data[0]-h.Min.data[0])/dx), 0), h.Columns-2)
+	j := minInt(maxInt(int((start.data[2]-h.Min.data[2])/dz), 0), h.Rows-2)
+
+	stepI, nextX, deltaX := gridStep(r.Origin.data[0], r.Direction.data[0], h.Min.data[0], dx, i)
+	stepJ, nextZ, deltaZ := gridStep(r.Origin.data[2], r.Direction.data[2], h.Min.data[2], dz, j)
+
+	for {
+		if hit, record := h.cellHit(r, i, j, tmin, tmax); hit {
+			return true, record
+		}
+
+		if nextX < nextZ {
+			if nextX > t1 {
+				break
+			}
+			i += stepI
+			nextX += deltaX
+		} else {
+			if nextZ > t1 {
+				break
+			}
+			j += stepJ
+			nextZ += deltaZ
+		}
+		if i < 0 || i >= h.Columns-1 || j < 0 || j >= h.Rows-1 {
+			break
+		}
+	}
+
+	return false, nil
+}
+
+// gridStep sets up one axis of the grid traversal: the cell step direction,
+// the ray parameter of the next cell boundary and the parameter span of one
+// cell.
+func gridStep(origin, direction, min, size float64, cell int) (int, float64, float64) {
+	if direction > 0 {
+		boundary := min + float64(cell+1)*size
+		return 1, (boundary - origin) / direction, size / direction
+	}
+	if direction < 0 {
+		boundary := min + float64(cell)*size
+		return -1, (boundary - origin) / direction, -size / direction
+	}
+	return 0, math.Inf(1), math.Inf(1)
+}
+
+func (h *Heightfield) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return true, h.box
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -2,13 +2,30 @@ package models
 
 type Hitable interface {
 	Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord)
+	BoundingBox(t0, t1 float64) (bool, *AABB)
 }
 
+// HitRecord describes a ray hit. Tangent and Bitangent point along
+// increasing U and V on the surface. They need not be unit length or
+// orthogonal to N, and are nil for shapes without a texture parameterisation.
 type HitRecord struct {
-	T        float64
-	P        *Vector
-	N        *Vector
-	Material Material
+	T                  float64
+	P                  *Vector
+	N                  *Vector
+	U, V               float64
+	Tangent, Bitangent *Vector
+	Color              *Vector
+	Material           Material
+	ObjectID           uint32
+}
+
+// FacingNormal returns the surface normal flipped, if needed, to point against
+// the incoming ray. Open surfaces such as triangles can be hit from either side.
+func (h *HitRecord) FacingNormal(r *Ray) *Vector {
+	if r.Direction.Dot(h.N) > 0 {
+		return h.N.Copy().Negate()
+	}
+	return h.N
 }
 
 type HitableList struct {
@@ -33,3 +50,19 @@ func (hl *HitableList) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
 	}
 	return hitAnything, record
 }
+
+func (hl *HitableList) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	if len(hl.List) == 0 {
+		return false, nil
+	}
+
+	var box *AABB
+	for _, hitable := range hl.List {
+		bounded, hitableBox := hitable.BoundingBox(t0, t1)
+		if !bounded {
+			return false, nil
+		}
+		box = growBox(box, hitableBox)
+	}
+	return true, box
+}
@@ -0,0 +1,53 @@
+package models
+
+// Instance places a shared object in the world through an affine transform.
+// Rays are moved into the object's space, so the object itself is never
+// copied and may be instanced any number of times.
+type Instance struct {
+	Object    Hitable
+	Transform *Matrix4
+	Inverse   *Matrix4
+}
+
+func NewInstance(object Hitable, transform *Matrix4) *Instance {
+	invertible, inverse := transform.Inverse()
+	if !invertible {
+		panic("Instance transform is not invertible")
+	}
+
+	return &Instance{
+		Object:    object,
+		Transform: transform,
+		Inverse:   inverse,
+	}
+}
+
+func (in *Instance) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	localRay := &Ray{
+		Origin:    in.Inverse.TransformPoint(r.Origin),
+		Direction: in.Inverse.TransformDirection(r.Direction),
+		Time:      r.Time,
+		Rand:      r.Rand,
+	}
+
+	hit, record := in.Object.Hit(localRay, tmin, tmax)
+	if !hit {
+		return false, nil
+	}
+
+	record.P = r.PointAtParameter(record.T)
+	record.N = in.Inverse.TransformNormal(record.N)
+	if record.Tangent != nil {
+		record.Tangent = in.Transform.TransformDirection(record.Tangent)
+		record.Bitangent = in.Transform.TransformDirection(record.Bitangent)
+	}
+	return true, record
+}
+
+func (in *Instance) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	bounded, box := in.Object.BoundingBox(t0, t1)
+	if !bounded {
+		return false, nil
+	}
+	return true, in.Transform.TransformBox(box)
+}
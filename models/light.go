@@ -0,0 +1,164 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+)
+
+// lightEpsilon matches the minimum ray distance the tracer uses.
+const lightEpsilon = 0.0001
+
+// Emitter is a shape that can be sampled as a light source. SampleDirection
+// picks a direction from origin towards the shape and DirectionPDF gives the
+// solid angle density of picking a direction that way.
+type Emitter interface {
+	Hitable
+	IsLight() bool
+	SampleDirection(origin *Vector, rng *rand.Rand) *Vector
+	DirectionPDF(origin, direction *Vector) float64
+}
+
+// LightList holds the emitters of a scene that are sampled directly, along
+// with the environment when it has any light. Each sample picks one of them
+// uniformly at random. Delta lights are instead all evaluated at every
+// surface.
+type LightList struct {
+	Emitters    []Emitter
+	Environment *Environment
+	Delta       []DeltaLight
+}
+
+// NewLightList collects the objects which are emitters with an emissive
+// material. Emitters nested in instances, CSG or media are not collected and
+// are only found by rays bouncing into them.
+func NewLightList(objects []Hitable) *LightList {
+	lights := &LightList{}
+	for _, object := range objects {
+		if emitter, ok := object.(Emitter); ok && emitter.IsLight() {
+			lights.Emitters = append(lights.Emitters, emitter)
+		}
+	}
+	return lights
+}
+
+// IsEmpty reports whether there are no emitters to sample.
+func (l *LightList) IsEmpty() bool {
+	return l.count() == 0
+}
+
+func (l *LightList) count() int {
+	if l.Environment != nil {
+		return len(l.Emitters) + 1
+	}
+	return len(l.Emitters)
+}
+
+func (l *LightList) SampleDirection(origin *Vector, rng *rand.Rand) *Vector {
+	i := rng.Intn(l.count())
+	if i == len(l.Emitters) {
+		return l.Environment.SampleDirection(rng)
+	}
+	return l.Emitters[i].SampleDirection(origin, rng)
+}
+
+func (l *LightList) DirectionPDF(origin, direction *Vector) float64 {
+	var pdf float64
+	for _, emitter := range l.Emitters {
+		pdf += emitter.DirectionPDF(origin, direction)
+	}
+	if l.Environment != nil {
+		pdf += l.Environment.DirectionPDF(direction)
+	}
+	return pdf / float64(l.count())
+}
+
+func (s *Sphere) IsLight() bool {
+	return s.Material.IsLight()
+}
+
+// SampleDirection picks a direction uniformly within the cone the sphere
+// covers as seen from origin, or any direction from inside the sphere.
+func (s *Sphere) SampleDirection(origin *Vector, rng *rand.Rand) *Vector {
+	toCenter := s.Center.Copy().SubtractVector(origin)
+	distance2 := toCenter.SquaredLength()
+	if distance2 <= s.Radius*s.Radius {
+		return RandomUnitVector(rng)
+	}
+
+	cosMax := math.Sqrt(1 - s.Radius*s.Radius/distance2)
+	cosTheta := 1 + rng.Float64()*(cosMax-1)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * rng.Float64()
+
+	w := toCenter.MakeUnitVector()
+	u, v := OrthonormalBasis(w)
+	return u.Scale(sinTheta*math.Cos(phi)).
+		AddScaledVector(v, sinTheta*math.Sin(phi)).
+		AddScaledVector(w, cosTheta)
+}
+
+func (s *Sphere) DirectionPDF(origin, direction *Vector) float64 {
+	if hit, _ := s.Hit(&Ray{Origin: origin, Direction: direction}, lightEpsilon, math.MaxFloat64); !hit {
+		return 0
+	}
+
+	distance2 := s.Center.Copy().SubtractVector(origin).SquaredLength()
+	if distance2 <= s.Radius*s.Radius {
+		return 1 / (4 * math.Pi)
+	}
+	cosMax := math.Sqrt(1 - s.Radius*s.Radius/distance2)
+	return 1 / (2 * math.Pi * (1 - cosMax))
+}
+
+func (q *Quad) IsLight() bool {
+	return q.Material.IsLight()
+}
+
+// SampleDirection picks a point uniformly on the quad and returns the
+// direction from origin to it.
+func (q *Quad) SampleDirection(origin *Vector, rng *rand.Rand) *Vector {
+	return q.Corner.Copy().
+		AddScaledVector(q.U, rng.Float64()).
+		AddScaledVector(q.V, rng.Float64()).
+		SubtractVector(origin)
+}
+
+func (q *Quad) DirectionPDF(origin, direction *Vector) float64 {
+	return areaDirectionPDF(q, origin, direction, q.area)
+}
+
+func (d *Disk) IsLight() bool {
+	return d.Material.IsLight()
+}
+
+// SampleDirection picks a point uniformly on the disk and returns the
+// direction from origin to it.
+func (d *Disk) SampleDirection(origin *Vector, rng *rand.Rand) *Vector {
+	r := d.Radius * math.Sqrt(rng.Float64())
+	phi := 2 * math.Pi * rng.Float64()
+	return d.Center.Copy().
+		AddScaledVector(d.Tangent, r*math.Cos(phi)).
+		AddScaledVector(d.Binormal, r*math.Sin(phi)).
+		SubtractVector(origin)
+}
+
+func (d *Disk) DirectionPDF(origin, direction *Vector) float64 {
+	return areaDirectionPDF(d, origin, direction, math.Pi*d.Radius*d.Radius)
+}
+
+// areaDirectionPDF converts the density of sampling a flat shape uniformly
+// by area into a density over directions from origin.
+func areaDirectionPDF(shape Hitable, origin, direction *Vector, area float64) float64 {
+	hit, record := shape.Hit(&Ray{Origin: origin, Direction: direction}, lightEpsilon, math.MaxFloat64)
+	if !hit {
+		return 0
+	}
+
+	length2 := direction.SquaredLength()
+	distance2 := record.T * record.T * length2
+	cosine := math.Abs(direction.Dot(record.N)) / math.Sqrt(length2)
+	if cosine < 1e-9 {
+		return 0
+	}
+	return distance2 / (cosine * area)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"math/rand"
 
 	"github.com/DheerendraRathor/GoTracer/utils"
@@ -8,46 +9,166 @@ import (
 
 type Material interface {
 	Scatter(*Ray, *HitRecord, *rand.Rand) (bool, *Vector, *Ray)
+	Emitted(*Ray, *HitRecord) *Vector
+	PerturbNormal(*HitRecord)
 	IsLight() bool
 }
 
+// baseMaterial is implemented by materials built on BaseMaterial, giving
+// access to the settings shared by all of them.
+type baseMaterial interface {
+	Material
+	base() *BaseMaterial
+}
+
+// Evaluator is implemented by materials whose scattering can be evaluated for
+// any pair of directions, which lets the tracer sample lights directly.
+// Evaluate returns the fraction of light arriving from direction that leaves
+// along the reversed ray, times the cosine at the surface, together with the
+// density Scatter samples direction with.
+type Evaluator interface {
+	Evaluate(ray *Ray, hitRecord *HitRecord, direction *Vector) (*Vector, float64)
+}
+
+// BaseMaterial holds what all materials share. NormalMap is a tangent space
+// normal map and BumpMap a grayscale height map scaled by BumpScale, both
+// laid out over the surface's texture coordinates. Any material glows with
+// Emission times EmissionStrength, from the front of the surface only when
+// OneSided is set. ID tells materials apart in the MaterialID pass.
 type BaseMaterial struct {
-	Albedo  *Vector
-	isLight bool
+	Albedo           Texture
+	VertexColors     bool
+	NormalMap        *Image
+	BumpMap          *Image
+	BumpScale        float64
+	Emission         Texture
+	EmissionStrength float64
+	OneSided         bool
+	ID               uint32
 }
 
-func NewBaseMaterial(albedo *Vector, isLight bool) *BaseMaterial {
+func NewBaseMaterial(albedo Texture) *BaseMaterial {
 	return &BaseMaterial{
-		Albedo:  albedo,
-		isLight: isLight,
+		Albedo: albedo,
 	}
 }
 
+// IsLight reports whether the material emits light.
 func (b *BaseMaterial) IsLight() bool {
-	return b.isLight
+	return b.Emission != nil
+}
+
+// Emitted returns the radiance the surface emits towards the origin of ray.
+func (b *BaseMaterial) Emitted(ray *Ray, hitRecord *HitRecord) *Vector {
+	if b.Emission == nil || (b.OneSided && ray.Direction.Dot(hitRecord.N) > 0) {
+		return NewEmptyVector()
+	}
+	return b.Emission.Value(hitRecord.U, hitRecord.V, hitRecord.P).Scale(b.EmissionStrength)
+}
+
+func (b *BaseMaterial) base() *BaseMaterial {
+	return b
+}
+
+// PerturbNormal replaces the normal of the hit with the one given by the
+// material's normal and bump maps, if any.
+func (b *BaseMaterial) PerturbNormal(hitRecord *HitRecord) {
+	if b.NormalMap == nil && b.BumpMap == nil {
+		return
+	}
+
+	tangent, bitangent := tangentFrame(hitRecord)
+	n := hitRecord.N
+
+	if b.NormalMap != nil {
+		c := b.NormalMap.Sample(hitRecord.U, hitRecord.V)
+		n = tangent.Copy().Scale(2*c.data[0]-1).
+			AddScaledVector(bitangent, 2*c.data[1]-1).
+			AddScaledVector(n, 2*c.data[2]-1).
+			MakeUnitVector()
+	}
+
+	if b.BumpMap != nil {
+		du, dv := 1/float64(b.BumpMap.Width), 1/float64(b.BumpMap.Height)
+		height := func(u, v float64) float64 {
+			c := b.BumpMap.Sample(u, v)
+			return (c.data[0] + c.data[1] + c.data[2]) / 3
+		}
+		u, v := hitRecord.U, hitRecord.V
+		slopeU := (height(u+du, v) - height(u-du, v)) / 2
+		slopeV := (height(u, v+dv) - height(u, v-dv)) / 2
+		n = n.Copy().
+			AddScaledVector(tangent, -b.BumpScale*slopeU).
+			AddScaledVector(bitangent, -b.BumpScale*slopeV).
+			MakeUnitVector()
+	}
+
+	hitRecord.N = n
+}
+
+// tangentFrame makes the tangents of a hit orthonormal to its normal, keeping
+// their directions, or picks an arbitrary frame if the hit has none.
+func tangentFrame(hitRecord *HitRecord) (*Vector, *Vector) {
+	n := hitRecord.N
+	if hitRecord.Tangent != nil {
+		tangent := hitRecord.Tangent.Copy().AddScaledVector(n, -n.Dot(hitRecord.Tangent))
+		bitangent := hitRecord.Bitangent.Copy().AddScaledVector(n, -n.Dot(hitRecord.Bitangent))
+		if tangent.SquaredLength() > 1e-12 {
+			tangent.MakeUnitVector()
+			bitangent.AddScaledVector(tangent, -tangent.Dot(bitangent))
+			if bitangent.SquaredLength() > 1e-12 {
+				return tangent, bitangent.MakeUnitVector()
+			}
+		}
+	}
+	return OrthonormalBasis(n)
+}
+
+// albedo returns the interpolated vertex colour of the hit when the material
+// asks for it and the geometry provides one, and the albedo texture otherwise.
+func (b *BaseMaterial) albedo(hitRecord *HitRecord) *Vector {
+	if b.VertexColors && hitRecord.Color != nil {
+		return hitRecord.Color.Copy()
+	}
+	return b.Albedo.Value(hitRecord.U, hitRecord.V, hitRecord.P)
 }
 
 type Lambertian struct {
 	*BaseMaterial
 }
 
-func NewLambertian(albedo *Vector) *Lambertian {
+func NewLambertian(albedo Texture) *Lambertian {
 	return &Lambertian{
-		BaseMaterial: NewBaseMaterial(albedo, false),
+		BaseMaterial: NewBaseMaterial(albedo),
 	}
 }
 
+// Scatter samples directions with a cosine distribution around the normal,
+// which is exactly proportional to the Lambertian reflectance.
 func (l *Lambertian) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bool, *Vector, *Ray) {
 
-	pN := RandomPointInUnitSphere(rng).
-		AddVector(hitRecord.N)
+	pN := RandomUnitVector(rng).
+		AddVector(hitRecord.FacingNormal(ray))
+	if pN.SquaredLength() < 1e-12 {
+		pN = hitRecord.FacingNormal(ray).Copy()
+	}
 
 	scattered := Ray{
 		Origin:    hitRecord.P,
 		Direction: pN,
+		Time:      ray.Time,
+		Rand:      rng,
 	}
 
-	return true, l.Albedo.Copy(), &scattered
+	return true, l.albedo(hitRecord), &scattered
+}
+
+func (l *Lambertian) Evaluate(ray *Ray, hitRecord *HitRecord, direction *Vector) (*Vector, float64) {
+	cosine := direction.Dot(hitRecord.FacingNormal(ray)) / direction.Length()
+	if cosine <= 0 {
+		return NewEmptyVector(), 0
+	}
+	return l.albedo(hitRecord).Scale(cosine / math.Pi), cosine / math.Pi
 }
 
 type Metal struct {
@@ -55,21 +176,24 @@ type Metal struct {
 	fuzz float64
 }
 
-func NewMetal(albedo *Vector, fuzz float64) *Metal {
+func NewMetal(albedo Texture, fuzz float64) *Metal {
 	return &Metal{
-		BaseMaterial: NewBaseMaterial(albedo, false),
+		BaseMaterial: NewBaseMaterial(albedo),
 		fuzz:         fuzz,
 	}
 }
 
 func (m *Metal) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bool, *Vector, *Ray) {
-	reflected := ray.Direction.Copy().Reflect(hitRecord.N).MakeUnitVector()
+	normal := hitRecord.FacingNormal(ray)
+	reflected := ray.Direction.Copy().Reflect(normal).MakeUnitVector()
 	scattered := Ray{
 		hitRecord.P,
 		reflected.AddScaledVector(RandomPointInUnitSphere(rng), m.fuzz),
+		ray.Time,
+		rng,
 	}
-	shouldScatter := scattered.Direction.Dot(hitRecord.N) > 0
-	return shouldScatter, m.Albedo.Copy(), &scattered
+	shouldScatter := scattered.Direction.Dot(normal) > 0
+	return shouldScatter, m.albedo(hitRecord), &scattered
 }
 
 type Dielectric struct {
@@ -102,31 +226,59 @@ func (d *Dielectric) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bo
 	}
 
 	if rng.Float64() < reflectionProb {
-		scattered = &Ray{hitRecord.P, reflected}
+		scattered = &Ray{hitRecord.P, reflected, ray.Time, rng}
 	} else {
-		scattered = &Ray{hitRecord.P, refractedVec}
+		scattered = &Ray{hitRecord.P, refractedVec, ray.Time, rng}
 	}
 
-	return true, d.Albedo.Copy(), scattered
+	return true, d.albedo(hitRecord), scattered
 }
 
-func NewDielectric(albedo *Vector, r float64) *Dielectric {
+func NewDielectric(albedo Texture, r float64) *Dielectric {
 	return &Dielectric{
-		BaseMaterial: NewBaseMaterial(albedo, false),
+		BaseMaterial: NewBaseMaterial(albedo),
 		RefIndex:     r,
 	}
 }
 
+// Light only emits, with its albedo as the emitted colour.
 type Light struct {
 	*BaseMaterial
 }
 
-func NewLight(albedo *Vector) *Light {
+func NewLight(albedo Texture) *Light {
+	base := NewBaseMaterial(albedo)
+	base.Emission = albedo
+	base.EmissionStrength = 1
 	return &Light{
-		BaseMaterial: NewBaseMaterial(albedo, true),
+		BaseMaterial: base,
 	}
 }
 
 func (l *Light) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bool, *Vector, *Ray) {
-	return false, l.Albedo.Copy(), nil
+	return false, nil, nil
+}
+
+type Isotropic struct {
+	*BaseMaterial
+}
+
+func NewIsotropic(albedo Texture) *Isotropic {
+	return &Isotropic{
+		BaseMaterial: NewBaseMaterial(albedo),
+	}
+}
+
+func (i *Isotropic) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bool, *Vector, *Ray) {
+	scattered := Ray{
+		Origin:    hitRecord.P,
+		Direction: RandomUnitVector(rng),
+		Time:      ray.Time,
+		Rand:      rng,
+	}
+	return true, i.albedo(hitRecord), &scattered
+}
+
+func (i *Isotropic) Evaluate(ray *Ray, hitRecord *HitRecord, direction *Vector) (*Vector, float64) {
+	return i.albedo(hitRecord).Scale(1 / (4 * math.Pi)), 1 / (4 * math.Pi)
 }
@@ -0,0 +1,154 @@
+package models
+
+import "math"
+
+// Matrix4 is a row major 4x4 matrix acting on column vectors.
+type Matrix4 [4][4]float64
+
+func IdentityMatrix() *Matrix4 {
+	return &Matrix4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+func NewMatrixFromSlice(values []float64) *Matrix4 {
+	m := &Matrix4{}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			m[i][j] = values[4*i+j]
+		}
+	}
+	return m
+}
+
+func TranslationMatrix(x, y, z float64) *Matrix4 {
+	m := IdentityMatrix()
+	m[0][3], m[1][3], m[2][3] = x, y, z
+	return m
+}
+
+func ScalingMatrix(x, y, z float64) *Matrix4 {
+	m := IdentityMatrix()
+	m[0][0], m[1][1], m[2][2] = x, y, z
+	return m
+}
+
+// RotationMatrix rotates by degrees about the X (0), Y (1) or Z (2) axis.
+func RotationMatrix(axis int, degrees float64) *Matrix4 {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	a, b := (axis+1)%3, (axis+2)%3
+	m := IdentityMatrix()
+	m[a][a], m[a][b] = cos, -sin
+	m[b][a], m[b][b] = sin, cos
+	return m
+}
+
+// Multiply returns m * n, the transform which applies n first and then m.
+func (m *Matrix4) Multiply(n *Matrix4) *Matrix4 {
+	result := &Matrix4{}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				result[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return result
+}
+
+func (m *Matrix4) Transpose() *Matrix4 {
+	result := &Matrix4{}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			result[i][j] = m[j][i]
+		}
+	}
+	return result
+}
+
+// Inverse uses Gauss-Jordan elimination with partial pivoting. It returns
+// false when the matrix is singular.
+func (m *Matrix4) Inverse() (bool, *Matrix4) {
+	a := *m
+	inv := *IdentityMatrix()
+
+	for col := 0; col < 4; col++ {
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return false, nil
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		scale := 1 / a[col][col]
+		for j := 0; j < 4; j++ {
+			a[col][j] *= scale
+			inv[col][j] *= scale
+		}
+
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			factor := a[row][col]
+			for j := 0; j < 4; j++ {
+				a[row][j] -= factor * a[col][j]
+				inv[row][j] -= factor * inv[col][j]
+			}
+		}
+	}
+
+	return true, &inv
+}
+
+func (m *Matrix4) TransformPoint(p *Vector) *Vector {
+	x, y, z := p.data[0], p.data[1], p.data[2]
+	return NewVector(
+		m[0][0]*x+m[0][1]*y+m[0][2]*z+m[0][3],
+		m[1][0]*x+m[1][1]*y+m[1][2]*z+m[1][3],
+		m[2][0]*x+m[2][1]*y+m[2][2]*z+m[2][3],
+	)
+}
+
+func (m *Matrix4) TransformDirection(v *Vector) *Vector {
+	x, y, z := v.data[0], v.data[1], v.data[2]
+	return NewVector(
+		m[0][0]*x+m[0][1]*y+m[0][2]*z,
+		m[1][0]*x+m[1][1]*y+m[1][2]*z,
+		m[2][0]*x+m[2][1]*y+m[2][2]*z,
+	)
+}
+
+// TransformNormal transforms n by the transpose of m. Passing the inverse of
+// a transform gives the correct normal transform for that transform.
+func (m *Matrix4) TransformNormal(n *Vector) *Vector {
+	x, y, z := n.data[0], n.data[1], n.data[2]
+	return NewVector(
+		m[0][0]*x+m[1][0]*y+m[2][0]*z,
+		m[0][1]*x+m[1][1]*y+m[2][1]*z,
+		m[0][2]*x+m[1][2]*y+m[2][2]*z,
+	).MakeUnitVector()
+}
+
+func (m *Matrix4) TransformBox(box *AABB) *AABB {
+	var result *AABB
+	for i := 0; i < 8; i++ {
+		corner := NewVector(box.Min.data[0], box.Min.data[1], box.Min.data[2])
+		for a := 0; a < 3; a++ {
+			if i&(1<<uint(a)) != 0 {
+				corner.data[a] = box.Max.data[a]
+			}
+		}
+		p := m.TransformPoint(corner)
+		result = growBox(result, NewAABB(p, p.Copy()))
+	}
+	return result
+}
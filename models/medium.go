@@ -0,0 +1,96 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ConstantMedium fills a closed boundary with a homogeneous participating
+// medium such as smoke. A ray passing through travels an exponentially
+// distributed distance before it scatters off a particle.
+type ConstantMedium struct {
+	Boundary Hitable
+	Density  float64
+	Phase    Material
+}
+
+func NewConstantMedium(boundary Hitable, density float64, albedo *Vector) *ConstantMedium {
+	return &ConstantMedium{
+		Boundary: boundary,
+		Density:  density,
+		Phase:    NewIsotropic(NewSolidTexture(albedo)),
+	}
+}
+
+func (c *ConstantMedium) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	hit, entry := c.Boundary.Hit(r, -math.MaxFloat64, math.MaxFloat64)
+	if !hit {
+		return false, nil
+	}
+	hit, exit := c.Boundary.Hit(r, entry.T+0.0001, math.MaxFloat64)
+	if !hit {
+		return false, nil
+	}
+
+	t0, t1 := math.Max(entry.T, tmin), math.Min(exit.T, tmax)
+	if t0 >= t1 {
+		return false, nil
+	}
+	if t0 < 0 {
+		t0 = 0
+	}
+
+	length := r.Direction.Length()
+	distance := -math.Log(1-r.Rand.Float64()) / c.Density
+	if distance > (t1-t0)*length {
+		return false, nil
+	}
+
+	t := t0 + distance/length
+	return true, &HitRecord{
+		T:        t,
+		P:        r.PointAtParameter(t),
+		N:        NewVector(1, 0, 0),
+		Material: c.Phase,
+	}
+}
+
+func (c *ConstantMedium) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return c.Boundary.BoundingBox(t0, t1)
+}
+
+// Fog is a homogeneous medium filling the whole scene. Rays which hit nothing
+// leave the fog after Distance, otherwise no light from the background could
+// ever get through.
+type Fog struct {
+	Density  float64
+	Distance float64
+	Phase    Material
+}
+
+func NewFog(density, distance float64, albedo *Vector) *Fog {
+	return &Fog{
+		Density:  density,
+		Distance: distance,
+		Phase:    NewIsotropic(NewSolidTexture(albedo)),
+	}
+}
+
+// Scatter samples how far a ray travels through the fog. When the ray
+// scatters before reaching the nearest surface hit at parameter tHit (or
+// before leaving the fog if didHit is false), it returns a record for
+// scattering off the fog at that point.
+func (f *Fog) Scatter(r *Ray, didHit bool, tHit float64, rng *rand.Rand) (bool, *HitRecord) {
+	length := r.Direction.Length()
+	t := -math.Log(1-rng.Float64()) / (f.Density * length)
+	if didHit && t >= tHit || !didHit && t*length >= f.Distance {
+		return false, nil
+	}
+
+	return true, &HitRecord{
+		T:        t,
+		P:        r.PointAtParameter(t),
+		N:        NewVector(1, 0, 0),
+		Material: f.Phase,
+	}
+}
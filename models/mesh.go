@@ -0,0 +1,108 @@
+package models
+
+type TriangleMesh struct {
+	Triangles []Hitable
+	BVH       *BVHNode
+}
+
+func NewTriangleMesh(triangles []Hitable) *TriangleMesh {
+	return &TriangleMesh{
+		Triangles: triangles,
+		BVH:       NewBVHNode(triangles, 0, 0),
+	}
+}
+
+func (m *TriangleMesh) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	return m.BVH.Hit(r, tmin, tmax)
+}
+
+func (m *TriangleMesh) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return m.BVH.BoundingBox(t0, t1)
+}
+
+// transformVertices moves mesh positions and normals into the world. The
+// transform must be invertible so normals can use its inverse transpose.
+func transformVertices(positions, normals []*Vector, transform *Matrix4) ([]*Vector, []*Vector) {
+	invertible, inverse := transform.Inverse()
+	if !invertible {
+		panic("Mesh transform is not invertible")
+	}
+
+	worldPositions := make([]*Vector, len(positions))
+	for i, p := range positions {
+		worldPositions[i] = transform.TransformPoint(p)
+	}
+
+	worldNormals := make([]*Vector, len(normals))
+	for i, n := range normals {
+		worldNormals[i] = inverse.TransformNormal(n)
+	}
+
+	return worldPositions, worldNormals
+}
+
+// NewTriangleMeshFromOBJ triangulates every face of the model as a fan and
+// looks up the material of each face by its usemtl group name.
+func NewTriangleMeshFromOBJ(model *OBJModel, transform *Matrix4, material func(group string) Material) *TriangleMesh {
+	positions, normals := transformVertices(model.Positions, model.Normals, transform)
+
+	var triangles []Hitable
+	for _, face := range model.Faces {
+		faceMaterial := material(face.Material)
+		for k := 1; k+1 < len(face.Corners); k++ {
+			corners := [3][3]int{face.Corners[0], face.Corners[k], face.Corners[k+1]}
+
+			triangle := &Triangle{
+				Material: faceMaterial,
+				HasUV:    true,
+			}
+			for c, corner := range corners {
+				triangle.Vertices[c] = positions[corner[0]]
+				if corner[1] >= 0 {
+					triangle.UVs[c] = model.UVs[corner[1]]
+				} else {
+					triangle.HasUV = false
+				}
+				if corner[2] >= 0 {
+					triangle.Normals[c] = normals[corner[2]]
+				}
+			}
+			triangles = append(triangles, triangle)
+		}
+	}
+
+	return NewTriangleMesh(triangles)
+}
+
+func NewTriangleMeshFromPLY(model *PLYModel, transform *Matrix4, material Material) *TriangleMesh {
+	positions, normals := transformVertices(model.Positions, model.Normals, transform)
+
+	hasNormals := len(normals) == len(positions)
+	hasColors := len(model.Colors) == len(positions)
+	hasUVs := len(model.UVs) == len(positions)
+
+	var triangles []Hitable
+	for _, face := range model.Faces {
+		for k := 1; k+1 < len(face); k++ {
+			triangle := &Triangle{
+				Material: material,
+				HasUV:    hasUVs,
+			}
+			for c, index := range [3]int{face[0], face[k], face[k+1]} {
+				triangle.Vertices[c] = positions[index]
+				if hasNormals {
+					triangle.Normals[c] = normals[index]
+				}
+				if hasColors {
+					triangle.Colors[c] = model.Colors[index]
+				}
+				if hasUVs {
+					triangle.UVs[c] = model.UVs[index]
+				}
+			}
+			triangles = append(triangles, triangle)
+		}
+	}
+
+	return NewTriangleMesh(triangles)
+}
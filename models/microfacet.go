@@ -0,0 +1,255 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ggxMinAlpha keeps perfectly smooth surfaces from making the distribution
+// degenerate.
+const ggxMinAlpha = 1e-3
+
+// ConductorPresets holds the complex refractive index, eta and k, of common
+// metals at red, green and blue wavelengths.
+var ConductorPresets = map[string][2][3]float64{
+	"Gold":      {{0.143, 0.374, 1.442}, {3.983, 2.385, 1.603}},
+	"Silver":    {{0.155, 0.117, 0.138}, {4.828, 3.122, 2.147}},
+	"Copper":    {{0.200, 0.924, 1.102}, {3.912, 2.452, 2.142}},
+	"Aluminium": {{1.657, 0.880, 0.521}, {9.224, 6.270, 4.837}},
+}
+
+// ggx is the GGX (Trowbridge-Reitz) microfacet distribution with Smith
+// shadowing. All directions are unit vectors and cosines are taken against
+// the macro surface normal.
+type ggx struct {
+	alpha float64
+}
+
+func newGGX(roughness float64) ggx {
+	return ggx{alpha: math.Max(roughness*roughness, ggxMinAlpha)}
+}
+
+func (g ggx) d(cosH float64) float64 {
+	if cosH <= 0 {
+		return 0
+	}
+	a2 := g.alpha * g.alpha
+	k := cosH*cosH*(a2-1) + 1
+	return a2 / (math.Pi * k * k)
+}
+
+func (g ggx) g1(cos float64) float64 {
+	cos = math.Abs(cos)
+	a2 := g.alpha * g.alpha
+	return 2 * cos / (cos + math.Sqrt(a2+(1-a2)*cos*cos))
+}
+
+// sample draws a microfacet normal around n with density d(cosH) * cosH.
+func (g ggx) sample(n *Vector, rng *rand.Rand) *Vector {
+	phi := 2 * math.Pi * rng.Float64()
+	xi := rng.Float64()
+	tan2 := g.alpha * g.alpha * xi / (1 - xi)
+	cosTheta := 1 / math.Sqrt(1+tan2)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+
+	tangent, bitangent := OrthonormalBasis(n)
+	return tangent.Scale(sinTheta*math.Cos(phi)).
+		AddScaledVector(bitangent, sinTheta*math.Sin(phi)).
+		AddScaledVector(n, cosTheta)
+}
+
+// fresnelDielectric is the unpolarised reflectance of light arriving at
+// cosine cosI onto a boundary with relative refractive index eta.
+func fresnelDielectric(cosI, eta float64) float64 {
+	sin2T := (1 - cosI*cosI) / (eta * eta)
+	if sin2T >= 1 {
+		return 1
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	rs := (cosI - eta*cosT) / (cosI + eta*cosT)
+	rp := (eta*cosI - cosT) / (eta*cosI + cosT)
+	return (rs*rs + rp*rp) / 2
+}
+
+// fresnelConductor is the unpolarised reflectance of a metal with complex
+// refractive index eta + ik for each colour channel.
+func fresnelConductor(cosI float64, eta, k *Vector) *Vector {
+	cos2 := cosI * cosI
+	sin2 := 1 - cos2
+	f := NewEmptyVector()
+	for c := 0; c < 3; c++ {
+		eta2, k2 := eta.data[c]*eta.data[c], k.data[c]*k.data[c]
+		t0 := eta2 - k2 - sin2
+		a2b2 := math.Sqrt(t0*t0 + 4*eta2*k2)
+		t1 := a2b2 + cos2
+		a := math.Sqrt(math.Max(0, (a2b2+t0)/2))
+		t2 := 2 * cosI * a
+		rs := (t1 - t2) / (t1 + t2)
+		t3 := cos2*a2b2 + sin2*sin2
+		t4 := t2 * sin2
+		rp := rs * (t3 - t4) / (t3 + t4)
+		f.data[c] = (rs + rp) / 2
+	}
+	return f
+}
+
+// Conductor is a rough metal. Reflection follows the microfacet model with
+// the Fresnel term of the metal's complex refractive index, tinted by Albedo.
+type Conductor struct {
+	*BaseMaterial
+	Roughness float64
+	Eta, K    *Vector
+	ggx       ggx
+}
+
+func NewConductor(albedo Texture, roughness float64, eta, k *Vector) *Conductor {
+	return &Conductor{
+		BaseMaterial: NewBaseMaterial(albedo),
+		Roughness:    roughness,
+		Eta:          eta,
+		K:            k,
+		ggx:          newGGX(roughness),
+	}
+}
+
+func (c *Conductor) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bool, *Vector, *Ray) {
+	n := hitRecord.FacingNormal(ray)
+	wo := ray.Direction.Copy().Negate().MakeUnitVector()
+	cosO := wo.Dot(n)
+	if cosO <= 0 {
+		return false, nil, nil
+	}
+
+	h := c.ggx.sample(n, rng)
+	cosOH := wo.Dot(h)
+	if cosOH <= 0 {
+		return false, nil, nil
+	}
+	wi := h.Copy().Scale(2 * cosOH).SubtractVector(wo)
+	cosI := wi.Dot(n)
+	if cosI <= 0 {
+		return false, nil, nil
+	}
+
+	// f * cos / pdf with pdf = d * cosH / (4 * cosOH)
+	weight := c.ggx.g1(cosO) * c.ggx.g1(cosI) * cosOH / (cosO * h.Dot(n))
+	attenuation := fresnelConductor(cosOH, c.Eta, c.K).Scale(weight).MultiplyVector(c.albedo(hitRecord))
+
+	return true, attenuation, &Ray{hitRecord.P, wi, ray.Time, rng}
+}
+
+// Evaluate returns the reflected fraction times the cosine for light arriving
+// from direction, along with the density Scatter samples that direction with.
+func (c *Conductor) Evaluate(ray *Ray, hitRecord *HitRecord, direction *Vector) (*Vector, float64) {
+	n := hitRecord.FacingNormal(ray)
+	wo := ray.Direction.Copy().Negate().MakeUnitVector()
+	wi := direction.Copy().MakeUnitVector()
+	cosO, cosI := wo.Dot(n), wi.Dot(n)
+	if cosO <= 0 || cosI <= 0 {
+		return NewEmptyVector(), 0
+	}
+
+	h := wo.Copy().AddVector(wi).MakeUnitVector()
+	cosH, cosOH := h.Dot(n), wo.Dot(h)
+	d := c.ggx.d(cosH)
+	value := fresnelConductor(cosOH, c.Eta, c.K).
+		Scale(d * c.ggx.g1(cosO) * c.ggx.g1(cosI) / (4 * cosO)).
+		MultiplyVector(c.albedo(hitRecord))
+	return value, d * cosH / (4 * cosOH)
+}
+
+// RoughDielectric is frosted glass. Like Dielectric it reflects or refracts
+// with the Fresnel probability, but about a microfacet normal rather than the
+// surface normal.
+type RoughDielectric struct {
+	*BaseMaterial
+	RefIndex  float64
+	Roughness float64
+	ggx       ggx
+}
+
+func NewRoughDielectric(albedo Texture, refIndex, roughness float64) *RoughDielectric {
+	return &RoughDielectric{
+		BaseMaterial: NewBaseMaterial(albedo),
+		RefIndex:     refIndex,
+		Roughness:    roughness,
+		ggx:          newGGX(roughness),
+	}
+}
+
+// sides returns the normal on the side of wo and the refractive indices on
+// that side and the other.
+func (d *RoughDielectric) sides(wo *Vector, hitRecord *HitRecord) (*Vector, float64, float64) {
+	if wo.Dot(hitRecord.N) < 0 {
+		return hitRecord.N.Copy().Negate(), d.RefIndex, 1
+	}
+	return hitRecord.N, 1, d.RefIndex
+}
+
+func (d *RoughDielectric) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bool, *Vector, *Ray) {
+	wo := ray.Direction.Copy().Negate().MakeUnitVector()
+	n, etaI, etaT := d.sides(wo, hitRecord)
+	cosO := wo.Dot(n)
+
+	h := d.ggx.sample(n, rng)
+	cosOH := wo.Dot(h)
+	if cosOH <= 0 {
+		return false, nil, nil
+	}
+
+	var wi *Vector
+	if rng.Float64() < fresnelDielectric(cosOH, etaT/etaI) {
+		wi = h.Copy().Scale(2 * cosOH).SubtractVector(wo)
+		if wi.Dot(n) <= 0 {
+			return false, nil, nil
+		}
+	} else {
+		var refracted bool
+		refracted, wi = Refract(wo.Copy().Negate(), h, etaI, etaT)
+		if !refracted || wi.MakeUnitVector().Dot(n) >= 0 {
+			return false, nil, nil
+		}
+	}
+
+	// The Fresnel choice cancels the Fresnel term, leaving the same weight
+	// for reflection and refraction.
+	weight := d.ggx.g1(cosO) * d.ggx.g1(wi.Dot(n)) * cosOH / (math.Abs(cosO) * h.Dot(n))
+	return true, d.albedo(hitRecord).Scale(weight), &Ray{hitRecord.P, wi, ray.Time, rng}
+}
+
+// Evaluate returns the scattered fraction times the cosine for light arriving
+// from direction, along with the density Scatter samples that direction with.
+func (d *RoughDielectric) Evaluate(ray *Ray, hitRecord *HitRecord, direction *Vector) (*Vector, float64) {
+	wo := ray.Direction.Copy().Negate().MakeUnitVector()
+	wi := direction.Copy().MakeUnitVector()
+	n, etaI, etaT := d.sides(wo, hitRecord)
+	cosO, cosI := wo.Dot(n), wi.Dot(n)
+	if cosO <= 0 || cosI == 0 {
+		return NewEmptyVector(), 0
+	}
+
+	if cosI > 0 {
+		h := wo.Copy().AddVector(wi).MakeUnitVector()
+		cosH, cosOH := h.Dot(n), wo.Dot(h)
+		f := fresnelDielectric(cosOH, etaT/etaI)
+		dh := d.ggx.d(cosH)
+		value := d.albedo(hitRecord).Scale(f * dh * d.ggx.g1(cosO) * d.ggx.g1(cosI) / (4 * cosO))
+		return value, f * dh * cosH / (4 * cosOH)
+	}
+
+	h := wo.Copy().Scale(etaI).AddScaledVector(wi, etaT).MakeUnitVector()
+	if h.Dot(n) < 0 {
+		h.Negate()
+	}
+	cosH, cosOH, cosIH := h.Dot(n), wo.Dot(h), wi.Dot(h)
+	if cosOH <= 0 || cosIH >= 0 {
+		return NewEmptyVector(), 0
+	}
+
+	f := fresnelDielectric(cosOH, etaT/etaI)
+	dh := d.ggx.d(cosH)
+	denom := etaI*cosOH + etaT*cosIH
+	jacobian := etaT * etaT * math.Abs(cosIH) / (denom * denom)
+	value := d.albedo(hitRecord).Scale((1 - f) * dh * d.ggx.g1(cosO) * d.ggx.g1(cosI) * cosOH * jacobian / cosO)
+	return value, (1 - f) * dh * cosH * jacobian
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type OBJFace struct {
+	// Each corner holds position, texture and normal indices. Missing
+	// texture or normal indices are -1.
+	Corners  [][3]int
+	Material string
+}
+
+type OBJModel struct {
+	Positions []*Vector
+	UVs       [][2]float64
+	Normals   []*Vector
+	Faces     []OBJFace
+}
+
+func LoadOBJ(filePath string) (*OBJModel, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	model := &OBJModel{}
+	material := ""
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "v":
+			v, err := parseOBJFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filePath, lineNumber, err)
+			}
+			model.Positions = append(model.Positions, NewVector(v[0], v[1], v[2]))
+		case "vt":
+			v, err := parseOBJFloats(fields[1:], 2)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filePath, lineNumber, err)
+			}
+			model.UVs = append(model.UVs, [2]float64{v[0], v[1]})
+		case "vn":
+			v, err := parseOBJFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filePath, lineNumber, err)
+			}
+			model.Normals = append(model.Normals, NewVector(v[0], v[1], v[2]).MakeUnitVector())
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("%s:%d: face needs at least 3 vertices", filePath, lineNumber)
+			}
+			face := OBJFace{Material: material}
+			for _, field := range fields[1:] {
+				corner, err := model.parseCorner(field)
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %v", filePath, lineNumber, err)
+				}
+				face.Corners = append(face.Corners, corner)
+			}
+			model.Faces = append(model.Faces, face)
+		case "usemtl":
+			material = strings.TrimSpace(strings.TrimPrefix(line, "usemtl"))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+func parseOBJFloats(fields []string, count int) ([]float64, error) {
+	if len(fields) < count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(fields))
+	}
+	values := make([]float64, count)
+	for i := range values {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseCorner reads a face corner of the form v, v/vt, v//vn or v/vt/vn.
+// Indices are 1-based and negative indices are relative to the end of the
+// list read so far.
+func (m *OBJModel) parseCorner(field string) ([3]int, error) {
+	corner := [3]int{-1, -1, -1}
+	counts := [3]int{len(m.Positions), len(m.UVs), len(m.Normals)}
+
+	for i, part := range strings.Split(field, "/") {
+		if i > 2 {
+			return corner, fmt.Errorf("invalid face corner %q", field)
+		}
+		if part == "" {
+			continue
+		}
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return corner, err
+		}
+		if index < 0 {
+			index += counts[i]
+		} else {
+			index--
+		}
+		if index < 0 || index >= counts[i] {
+			return corner, fmt.Errorf("index out of range in face corner %q", field)
+		}
+		corner[i] = index
+	}
+
+	if corner[0] < 0 {
+		return corner, fmt.Errorf("face corner %q has no position", field)
+	}
+
+	return corner, nil
+}
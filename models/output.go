@@ -0,0 +1,369 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	PNGFormat = "PNG"
+	EXRFormat = "EXR"
+	PFMFormat = "PFM"
+	HDRFormat = "HDR"
+)
+
+const (
+	HalfPixels  = "Half"
+	FloatPixels = "Float"
+)
+
+const (
+	NoCompression  = "None"
+	ZIPCompression = "ZIP"
+)
+
+// Validate panics on output settings the image can't be written with, so a
+// typo is reported before rendering rather than after.
+func (i *ImageInput) Validate() {
+	i.GetFormat()
+	i.GetPixelType()
+	i.GetCompression()
+	i.GetToneMapper()
+	i.validateAOVs()
+}
+
+// GetFormat returns Format when given, in any case, and otherwise the format
+// named by the extension of OutputFile, defaulting to PNG.
+func (i *ImageInput) GetFormat() string {
+	if i.Format != "" {
+		format := strings.ToUpper(i.Format)
+		switch format {
+		case PNGFormat, EXRFormat, PFMFormat, HDRFormat:
+		default:
+			panic(fmt.Sprintf("Got invalid image format: %s", i.Format))
+		}
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(i.OutputFile)) {
+	case ".exr":
+		return EXRFormat
+	case ".pfm":
+		return PFMFormat
+	case ".hdr":
+		return HDRFormat
+	}
+	return PNGFormat
+}
+
+// GetPixelType returns the EXR pixel type, defaulting to half floats.
+func (i *ImageInput) GetPixelType() string {
+	switch i.PixelType {
+	case "":
+		return HalfPixels
+	case HalfPixels, FloatPixels:
+		return i.PixelType
+	}
+	panic(fmt.Sprintf("Got invalid pixel type: %s", i.PixelType))
+}
+
+// GetCompression returns the EXR compression, defaulting to ZIP.
+func (i *ImageInput) GetCompression() string {
+	switch i.Compression {
+	case "":
+		return ZIPCompression
+	case NoCompression, ZIPCompression:
+		return i.Compression
+	}
+	panic(fmt.Sprintf("Got invalid compression: %s", i.Compression))
+}
+
+// WriteImage writes image to writer in the output format, tone mapping and
+// quantising it only for PNG.
+func (i *ImageInput) WriteImage(writer io.Writer, image *Image) error {
+	return i.writeImage(writer, image, i.GetToneMapper())
+}
+
+func (i *ImageInput) writeImage(writer io.Writer, image *Image, mapper *ToneMapper) error {
+	switch format := i.GetFormat(); format {
+	case PNGFormat:
+		return png.Encode(writer, image.Quantize(mapper))
+	case EXRFormat:
+		return WriteEXR(writer, image, i.GetPixelType(), i.GetCompression())
+	case PFMFormat:
+		return WritePFM(writer, image)
+	case HDRFormat:
+		return WriteHDR(writer, image)
+	default:
+		return fmt.Errorf("unsupported output format %s", format)
+	}
+}
+
+// exrLinesPerBlock is the number of scanlines ZIP compresses together.
+const exrLinesPerBlock = 16
+
+// WriteEXR writes a single part scanline OpenEXR file with R, G and B
+// channels of half or full floats, either uncompressed or ZIP compressed.
+func WriteEXR(writer io.Writer, image *Image, pixelType, compression string) error {
+	var exrPixelType int32
+	var pixelSize int
+	switch pixelType {
+	case HalfPixels:
+		exrPixelType, pixelSize = 1, 2
+	case FloatPixels:
+		exrPixelType, pixelSize = 2, 4
+	default:
+		return fmt.Errorf("unsupported EXR pixel type %s", pixelType)
+	}
+
+	var exrCompression byte
+	linesPerBlock := 1
+	switch compression {
+	case NoCompression:
+	case ZIPCompression:
+		exrCompression, linesPerBlock = 3, exrLinesPerBlock
+	default:
+		return fmt.Errorf("unsupported EXR compression %s", compression)
+	}
+
+	header := &bytes.Buffer{}
+	attribute := func(name, kind string, value []byte) {
+		header.WriteString(name + "\x00" + kind + "\x00")
+		binary.Write(header, binary.LittleEndian, int32(len(value)))
+		header.Write(value)
+	}
+
+	// Channels are listed in alphabetical order.
+	channels := &bytes.Buffer{}
+	for _, name := range []string{"B", "G", "R"} {
+		channels.WriteString(name + "\x00")
+		binary.Write(channels, binary.LittleEndian, [4]int32{exrPixelType, 0, 1, 1})
+	}
+	channels.WriteByte(0)
+
+	window := &bytes.Buffer{}
+	binary.Write(window, binary.LittleEndian, [4]int32{0, 0, int32(image.Width - 1), int32(image.Height - 1)})
+
+	float32Bytes := func(values ...float32) []byte {
+		buffer := &bytes.Buffer{}
+		binary.Write(buffer, binary.LittleEndian, values)
+		return buffer.Bytes()
+	}
+
+	header.Write([]byte{0x76, 0x2f, 0x31, 0x01, 2, 0, 0, 0})
+	attribute("channels", "chlist", channels.Bytes())
+	attribute("compression", "compression", []byte{exrCompression})
+	attribute("dataWindow", "box2i", window.Bytes())
+	attribute("displayWindow", "box2i", window.Bytes())
+	attribute("lineOrder", "lineOrder", []byte{0})
+	attribute("pixelAspectRatio", "float", float32Bytes(1))
+	attribute("screenWindowCenter", "v2f", float32Bytes(0, 0))
+	attribute("screenWindowWidth", "float", float32Bytes(1))
+	header.WriteByte(0)
+
+	// Each block holds its scanlines one after another, and each scanline
+	// holds the values of one channel after another.
+	var blocks [][]byte
+	lineSize := 3 * image.Width * pixelSize
+	for y := 0; y < image.Height; y += linesPerBlock {
+		lines := minInt(linesPerBlock, image.Height-y)
+		data := make([]byte, 0, lines*lineSize)
+		for j := y; j < y+lines; j++ {
+			for c := 2; c >= 0; c-- {
+				for i := 0; i < image.Width; i++ {
+					value := float32(image.Pixels[j*image.Width+i].data[c])
+					if pixelType == HalfPixels {
+						data = binary.LittleEndian.AppendUint16(data, float32ToHalf(value))
+					} else {
+						data = binary.LittleEndian.AppendUint32(data, math.Float32bits(value))
+					}
+				}
+			}
+		}
+		if compression == ZIPCompression {
+			data = zipEXRBlock(data)
+		}
+		blocks = append(blocks, data)
+	}
+
+	offset := uint64(header.Len() + 8*len(blocks))
+	for _, block := range blocks {
+		binary.Write(header, binary.LittleEndian, offset)
+		offset += uint64(8 + len(block))
+	}
+
+	output := bufio.NewWriter(writer)
+	output.Write(header.Bytes())
+	for k, block := range blocks {
+		binary.Write(output, binary.LittleEndian, [2]int32{int32(k * linesPerBlock), int32(len(block))})
+		output.Write(block)
+	}
+	return output.Flush()
+}
+
+// zipEXRBlock splits the bytes of a block into two interleaved halves, stores
+// the differences between neighbours and deflates the result. Blocks which
+// don't shrink are stored as they are.
+func zipEXRBlock(data []byte) []byte {
+	reordered := make([]byte, len(data))
+	half := (len(data) + 1) / 2
+	for k, value := range data {
+		if k%2 == 0 {
+			reordered[k/2] = value
+		} else {
+			reordered[half+k/2] = value
+		}
+	}
+	for k := len(reordered) - 1; k > 0; k-- {
+		reordered[k] = byte(int(reordered[k]) - int(reordered[k-1]) + 128)
+	}
+
+	compressed := &bytes.Buffer{}
+	zipWriter := zlib.NewWriter(compressed)
+	zipWriter.Write(reordered)
+	zipWriter.Close()
+	if compressed.Len() >= len(data) {
+		return data
+	}
+	return compressed.Bytes()
+}
+
+// float32ToHalf converts to an IEEE half float, rounding to nearest even.
+func float32ToHalf(value float32) uint16 {
+	bits := math.Float32bits(value)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int(bits>>23&0xff) - 127 + 15
+	mantissa := bits & 0x7fffff
+
+	switch {
+	case bits&0x7fffffff > 0x7f800000:
+		return sign | 0x7e00
+	case exponent >= 0x1f:
+		return sign | 0x7c00
+	case exponent <= 0:
+		if exponent < -10 {
+			return sign
+		}
+		// Subnormal halves keep the implicit leading bit in the mantissa.
+		mantissa |= 0x800000
+		shift := uint(14 - exponent)
+		half := mantissa >> shift
+		rest := mantissa & (1<<shift - 1)
+		midpoint := uint32(1) << (shift - 1)
+		if rest > midpoint || rest == midpoint && half&1 == 1 {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	half := uint32(exponent)<<10 | mantissa>>13
+	rest := mantissa & 0x1fff
+	if rest > 0x1000 || rest == 0x1000 && half&1 == 1 {
+		// Rounding may carry into the exponent, up to infinity.
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// WritePFM writes a colour portable float map, little endian and bottom row
+// first.
+func WritePFM(writer io.Writer, image *Image) error {
+	output := bufio.NewWriter(writer)
+	fmt.Fprintf(output, "PF\n%d %d\n-1.0\n", image.Width, image.Height)
+	row := make([]float32, 3*image.Width)
+	for j := image.Height - 1; j >= 0; j-- {
+		for i := 0; i < image.Width; i++ {
+			for c := 0; c < 3; c++ {
+				row[3*i+c] = float32(image.Pixels[j*image.Width+i].data[c])
+			}
+		}
+		binary.Write(output, binary.LittleEndian, row)
+	}
+	return output.Flush()
+}
+
+// WriteHDR writes a run length encoded Radiance RGBE picture.
+func WriteHDR(writer io.Writer, image *Image) error {
+	output := bufio.NewWriter(writer)
+	fmt.Fprintf(output, "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", image.Height, image.Width)
+
+	scanline := make([]byte, 4*image.Width)
+	for j := 0; j < image.Height; j++ {
+		for i := 0; i < image.Width; i++ {
+			rgbe := vectorToRGBE(image.Pixels[j*image.Width+i])
+			copy(scanline[4*i:], rgbe[:])
+		}
+		writeHDRScanline(output, scanline, image.Width)
+	}
+	return output.Flush()
+}
+
+func vectorToRGBE(color *Vector) [4]byte {
+	m := math.Max(color.data[0], math.Max(color.data[1], color.data[2]))
+	if m < 1e-32 || math.IsNaN(m) {
+		return [4]byte{}
+	}
+	if math.IsInf(m, 1) {
+		m = math.MaxFloat32
+	}
+	fraction, exponent := math.Frexp(m)
+	if exponent > 127 {
+		return [4]byte{255, 255, 255, 255}
+	}
+	scale := fraction * 256 / m
+	var rgbe [4]byte
+	for c := 0; c < 3; c++ {
+		rgbe[c] = byte(math.Max(0, color.data[c]) * scale)
+	}
+	rgbe[3] = byte(exponent + 128)
+	return rgbe
+}
+
+// writeHDRScanline run length encodes each channel of scanline separately,
+// or writes it flat when it is too narrow or wide for that.
+func writeHDRScanline(output *bufio.Writer, scanline []byte, width int) {
+	if width < 8 || width > 0x7fff {
+		output.Write(scanline)
+		return
+	}
+
+	output.Write([]byte{2, 2, byte(width >> 8), byte(width)})
+	for c := 0; c < 4; c++ {
+		for i := 0; i < width; {
+			// Find the next run of at least four equal values.
+			start := i
+			run := 1
+			for start < width {
+				run = 1
+				for start+run < width && run < 127 && scanline[4*(start+run)+c] == scanline[4*start+c] {
+					run++
+				}
+				if run >= 4 {
+					break
+				}
+				start += run
+			}
+
+			for i < start {
+				count := minInt(start-i, 128)
+				output.WriteByte(byte(count))
+				for k := 0; k < count; k++ {
+					output.WriteByte(scanline[4*(i+k)+c])
+				}
+				i += count
+			}
+			if start < width {
+				output.WriteByte(byte(128 + run))
+				output.WriteByte(scanline[4*start+c])
+				i = start + run
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+func TestFloat32ToHalf(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float32
+		want  uint16
+	}{
+		{"zero", 0, 0x0000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"one", 1, 0x3c00},
+		{"minus two", -2, 0xc000},
+		{"tenth", 0.1, 0x2e66},
+		{"largest", 65504, 0x7bff},
+		{"rounds to infinity", 65520, 0x7c00},
+		{"overflow", 1e6, 0x7c00},
+		{"infinity", float32(math.Inf(1)), 0x7c00},
+		{"negative infinity", float32(math.Inf(-1)), 0xfc00},
+		{"NaN", float32(math.NaN()), 0x7e00},
+		{"smallest normal", float32(math.Ldexp(1, -14)), 0x0400},
+		{"smallest subnormal", float32(math.Ldexp(1, -24)), 0x0001},
+		{"subnormal tie to even", float32(math.Ldexp(1, -25)), 0x0000},
+		{"subnormal tie up", float32(math.Ldexp(3, -25)), 0x0002},
+		{"underflow", float32(math.Ldexp(1, -30)), 0x0000},
+		{"tie to even", float32(1 + math.Ldexp(1, -11)), 0x3c00},
+		{"tie up", float32(1 + math.Ldexp(3, -11)), 0x3c02},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := float32ToHalf(test.value); got != test.want {
+				t.Errorf("float32ToHalf(%v) = %#04x, want %#04x", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+// unzipEXRBlock undoes zipEXRBlock as an OpenEXR reader does.
+func unzipEXRBlock(t *testing.T, block []byte, size int) []byte {
+	if len(block) == size {
+		return block
+	}
+	zipReader, err := zlib.NewReader(bytes.NewReader(block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reordered, err := ioutil.ReadAll(zipReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k := 1; k < len(reordered); k++ {
+		reordered[k] = byte(int(reordered[k-1]) + int(reordered[k]) - 128)
+	}
+	data := make([]byte, len(reordered))
+	half := (len(reordered) + 1) / 2
+	for k := range data {
+		if k%2 == 0 {
+			data[k] = reordered[k/2]
+		} else {
+			data[k] = reordered[half+k/2]
+		}
+	}
+	return data
+}
+
+func TestZipEXRBlock(t *testing.T) {
+	ramp := make([]byte, 513)
+	for k := range ramp {
+		ramp[k] = byte(k * 7 / 3)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		compressed bool
+	}{
+		{"ramp", ramp, true},
+		{"constant", bytes.Repeat([]byte{0x3c}, 200), true},
+		{"short", []byte{1, 200, 3}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block := zipEXRBlock(append([]byte(nil), test.data...))
+			if compressed := len(block) < len(test.data); compressed != test.compressed {
+				t.Errorf("got %d bytes from %d, want compressed %t", len(block), len(test.data), test.compressed)
+			}
+			if got := unzipEXRBlock(t, block, len(test.data)); !bytes.Equal(got, test.data) {
+				t.Errorf("got %v back, want %v", got, test.data)
+			}
+		})
+	}
+}
+
+func TestHDRRoundTrip(t *testing.T) {
+	colors := []*Vector{
+		NewVector(0, 0, 0),
+		NewVector(1, 1, 1),
+		NewVector(0.5, 0.25, 0.125),
+		NewVector(1000, 2, 0.001),
+		NewVector(1e-5, 3e-5, 2e-5),
+		NewVector(-1, 0.5, 0.5),
+	}
+
+	// Narrow images are written flat and wide ones run length encoded, with
+	// runs of equal pixels as well as literal stretches.
+	for _, width := range []int{3, 40} {
+		image := NewFramebuffer(width, 2)
+		for k := range image.Pixels {
+			image.Pixels[k] = colors[k/4%len(colors)]
+		}
+
+		buffer := &bytes.Buffer{}
+		if err := WriteHDR(buffer, image); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readHDR(bufio.NewReader(buffer))
+		if err != nil {
+			t.Fatalf("width %d: %v", width, err)
+		}
+		if got.Width != image.Width || got.Height != image.Height {
+			t.Fatalf("width %d: got %dx%d image", width, got.Width, got.Height)
+		}
+
+		for k, want := range image.Pixels {
+			// RGBE keeps 8 bits of mantissa relative to the largest channel.
+			largest := math.Max(want.data[0], math.Max(want.data[1], want.data[2]))
+			for c := 0; c < 3; c++ {
+				if diff := math.Abs(got.Pixels[k].data[c] - math.Max(0, want.data[c])); diff > largest/128 {
+					t.Errorf("width %d: pixel %d is %v, want %v", width, k, got.Pixels[k], want)
+					break
+				}
+			}
+		}
+	}
+}
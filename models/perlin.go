@@ -0,0 +1,78 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+)
+
+const perlinPoints = 256
+
+// Perlin is gradient noise over random unit vectors at the integer lattice.
+// The same seed always gives the same noise.
+type Perlin struct {
+	gradients           [perlinPoints]*Vector
+	permX, permY, permZ [perlinPoints]int
+}
+
+func NewPerlin(seed int64) *Perlin {
+	rng := rand.New(rand.NewSource(seed))
+	p := &Perlin{}
+	for i := range p.gradients {
+		p.gradients[i] = RandomUnitVector(rng)
+	}
+	for _, perm := range []*[perlinPoints]int{&p.permX, &p.permY, &p.permZ} {
+		for i, k := range rng.Perm(perlinPoints) {
+			perm[i] = k
+		}
+	}
+	return p
+}
+
+// Noise returns a smooth value between about -1 and 1.
+func (p *Perlin) Noise(point *Vector) float64 {
+	var cell [3]int
+	var frac, smooth [3]float64
+	for a := 0; a < 3; a++ {
+		floor := math.Floor(point.data[a])
+		cell[a] = int(floor)
+		frac[a] = point.data[a] - floor
+		smooth[a] = frac[a] * frac[a] * (3 - 2*frac[a])
+	}
+
+	var sum float64
+	offset := NewEmptyVector()
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			for k := 0; k < 2; k++ {
+				gradient := p.gradients[p.permX[(cell[0]+i)&(perlinPoints-1)]^
+					p.permY[(cell[1]+j)&(perlinPoints-1)]^
+					p.permZ[(cell[2]+k)&(perlinPoints-1)]]
+				offset.Update(frac[0]-float64(i), frac[1]-float64(j), frac[2]-float64(k))
+				sum += perlinWeight(i, smooth[0]) * perlinWeight(j, smooth[1]) * perlinWeight(k, smooth[2]) *
+					gradient.Dot(offset)
+			}
+		}
+	}
+	return sum
+}
+
+func perlinWeight(corner int, t float64) float64 {
+	if corner == 1 {
+		return t
+	}
+	return 1 - t
+}
+
+// Turbulence is the magnitude of depth octaves of noise summed, each at twice
+// the frequency and half the weight of the one before.
+func (p *Perlin) Turbulence(point *Vector, depth int) float64 {
+	var sum float64
+	q := point.Copy()
+	weight := 1.0
+	for i := 0; i < depth; i++ {
+		sum += weight * p.Noise(q)
+		weight *= 0.5
+		q.Scale(2)
+	}
+	return math.Abs(sum)
+}
@@ -1,41 +1,101 @@
 package models
 
-import "math"
-
-func (v *Vector) Gamma2() {
-	v.data[0] = math.Sqrt(v.data[0])
-	v.data[1] = math.Sqrt(v.data[1])
-	v.data[2] = math.Sqrt(v.data[2])
-}
+import (
+	"image"
+	"image/color"
+	"math"
+)
 
+// Pixel is the linear colour traced for column I and row J of the image,
+// with rows counted from the top, and its value in each AOV asked for.
 type Pixel struct {
-	Color [3]uint8
+	Color [3]float64
 	I, J  int
+	AOVs  [][3]float64
 }
 
 func (v *Vector) ToPixel(i, j int) *Pixel {
-	r := v.X() * 255.99
-	if r > 255 {
-		r = 255
+	return &Pixel{
+		Color: v.data,
+		I:     i,
+		J:     j,
 	}
+}
 
-	g := v.Y() * 255.99
-	if g > 255 {
-		g = 255
+// MakeFinite replaces NaN with 0 and infinities with the largest float in the
+// colour and every AOV, since JSON can't encode them.
+func (p *Pixel) MakeFinite() {
+	makeFinite(&p.Color)
+	for k := range p.AOVs {
+		makeFinite(&p.AOVs[k])
 	}
+}
 
-	b := v.Z() * 255.99
-	if b > 255 {
-		b = 255
+func makeFinite(values *[3]float64) {
+	for k, value := range values {
+		switch {
+		case math.IsNaN(value):
+			values[k] = 0
+		case math.IsInf(value, 1):
+			values[k] = math.MaxFloat64
+		case math.IsInf(value, -1):
+			values[k] = -math.MaxFloat64
+		}
 	}
+}
 
-	return &Pixel{
-		[3]uint8{
-			uint8(r),
-			uint8(g),
-			uint8(b),
-		},
-		i,
-		j,
+// NewFramebuffer returns a black image to collect traced pixels in.
+func NewFramebuffer(width, height int) *Image {
+	framebuffer := &Image{
+		Width:  width,
+		Height: height,
+		Pixels: make([]*Vector, width*height),
+	}
+	for k := range framebuffer.Pixels {
+		framebuffer.Pixels[k] = NewEmptyVector()
+	}
+	return framebuffer
+}
+
+func (t *Image) SetPixel(pixel *Pixel) {
+	t.Pixels[pixel.J*t.Width+pixel.I] = NewVectorFromArray(pixel.Color)
+}
+
+// Quantize converts the image to 8 bits per channel through mapper. Without
+// a white point, extended Reinhard maps the brightest finite pixel to white.
+func (t *Image) Quantize(mapper *ToneMapper) *image.RGBA {
+	if mapper.WhitePoint <= 0 {
+		whiteMapper := *mapper
+		whiteMapper.WhitePoint = hableWhitePoint
+		if mapper.Operator == ExtendedReinhardToneMapping {
+			whiteMapper.WhitePoint = 1
+			exposure := math.Exp2(mapper.Exposure)
+			for _, pixel := range t.Pixels {
+				if l := luminance(pixel) * exposure; !math.IsNaN(l) && !math.IsInf(l, 0) {
+					whiteMapper.WhitePoint = math.Max(whiteMapper.WhitePoint, l)
+				}
+			}
+		}
+		mapper = &whiteMapper
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, t.Width, t.Height))
+	for j := 0; j < t.Height; j++ {
+		for i := 0; i < t.Width; i++ {
+			c := mapper.Map(t.Pixels[j*t.Width+i])
+			rgba.SetRGBA(i, j, color.RGBA{toUint8(c.data[0]), toUint8(c.data[1]), toUint8(c.data[2]), 255})
+		}
+	}
+	return rgba
+}
+
+func toUint8(value float64) uint8 {
+	value *= 255.99
+	if value > 255 {
+		return 255
+	}
+	if value < 0 || math.IsNaN(value) {
+		return 0
 	}
+	return uint8(value)
 }
@@ -0,0 +1,194 @@
+package models
+
+import "math"
+
+const planeBoxPadding = 1e-4
+
+// intersectPlane returns the ray parameter at which r crosses the plane
+// through point with the given unit normal.
+func intersectPlane(r *Ray, point, normal *Vector, tmin, tmax float64) (bool, float64) {
+	denom := normal.Dot(r.Direction)
+	if math.Abs(denom) < 1e-12 {
+		return false, 0
+	}
+	t := (normal.Dot(point) - normal.Dot(r.Origin)) / denom
+	if t <= tmin || t >= tmax {
+		return false, 0
+	}
+	return true, t
+}
+
+type Plane struct {
+	Point    *Vector
+	Normal   *Vector
+	Tangent  *Vector
+	Binormal *Vector
+	Material Material
+}
+
+func NewPlane(point, normal *Vector, material Material) *Plane {
+	normal = normal.Copy().MakeUnitVector()
+	tangent, binormal := OrthonormalBasis(normal)
+	return &Plane{
+		Point:    point,
+		Normal:   normal,
+		Tangent:  tangent,
+		Binormal: binormal,
+		Material: material,
+	}
+}
+
+// Hit reports texture coordinates as distances along the plane's tangent and
+// binormal from its reference point, so textures tile in world units.
+func (p *Plane) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	hit, t := intersectPlane(r, p.Point, p.Normal, tmin, tmax)
+	if !hit {
+		return false, nil
+	}
+
+	point := r.PointAtParameter(t)
+	local := point.Copy().SubtractVector(p.Point)
+
+	return true, &HitRecord{
+		T:         t,
+		P:         point,
+		N:         p.Normal,
+		U:         local.Dot(p.Tangent),
+		V:         local.Dot(p.Binormal),
+		Tangent:   p.Tangent,
+		Bitangent: p.Binormal,
+		Material:  p.Material,
+	}
+}
+
+func (p *Plane) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return false, nil
+}
+
+type Disk struct {
+	Center   *Vector
+	Normal   *Vector
+	Radius   float64
+	Tangent  *Vector
+	Binormal *Vector
+	Material Material
+}
+
+func NewDisk(center, normal *Vector, radius float64, material Material) *Disk {
+	normal = normal.Copy().MakeUnitVector()
+	tangent, binormal := OrthonormalBasis(normal)
+	return &Disk{
+		Center:   center,
+		Normal:   normal,
+		Radius:   radius,
+		Tangent:  tangent,
+		Binormal: binormal,
+		Material: material,
+	}
+}
+
+func (d *Disk) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	hit, t := intersectPlane(r, d.Center, d.Normal, tmin, tmax)
+	if !hit {
+		return false, nil
+	}
+
+	point := r.PointAtParameter(t)
+	local := point.Copy().SubtractVector(d.Center)
+	if local.SquaredLength() > d.Radius*d.Radius {
+		return false, nil
+	}
+
+	return true, &HitRecord{
+		T:         t,
+		P:         point,
+		N:         d.Normal,
+		U:         0.5 + local.Dot(d.Tangent)/(2*d.Radius),
+		V:         0.5 + local.Dot(d.Binormal)/(2*d.Radius),
+		Tangent:   d.Tangent,
+		Bitangent: d.Binormal,
+		Material:  d.Material,
+	}
+}
+
+func (d *Disk) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return true, diskBoundingBox(d.Center, d.Normal, d.Radius)
+}
+
+// Quad is the parallelogram spanned by edges U and V from Corner. Its normal
+// follows U x V and its texture coordinates run from 0 to 1 along each edge.
+type Quad struct {
+	Corner   *Vector
+	U, V     *Vector
+	Normal   *Vector
+	Material Material
+	w        *Vector
+	area     float64
+}
+
+func NewQuad(corner, u, v *Vector, material Material) *Quad {
+	n := NewEmptyVector().VectorCrossProduct(u, v)
+	return &Quad{
+		Corner:   corner,
+		U:        u,
+		V:        v,
+		Normal:   n.Copy().MakeUnitVector(),
+		Material: material,
+		w:        n.Copy().Scale(1 / n.Dot(n)),
+		area:     n.Length(),
+	}
+}
+
+// NewAxisAlignedQuad builds a rectangle in the "XY", "YZ" or "XZ" plane at
+// the given offset along the remaining axis, facing that axis' positive
+// direction. min and max are the rectangle corners in the plane's own axes.
+func NewAxisAlignedQuad(plane string, min, max [2]float64, offset float64, material Material) *Quad {
+	d0, d1 := max[0]-min[0], max[1]-min[1]
+	switch plane {
+	case "XY":
+		return NewQuad(NewVector(min[0], min[1], offset), NewVector(d0, 0, 0), NewVector(0, d1, 0), material)
+	case "YZ":
+		return NewQuad(NewVector(offset, min[0], min[1]), NewVector(0, d0, 0), NewVector(0, 0, d1), material)
+	case "XZ":
+		return NewQuad(NewVector(min[0], offset, min[1]), NewVector(0, 0, d1), NewVector(d0, 0, 0), material)
+	}
+	return nil
+}
+
+func (q *Quad) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	hit, t := intersectPlane(r, q.Corner, q.Normal, tmin, tmax)
+	if !hit {
+		return false, nil
+	}
+
+	point := r.PointAtParameter(t)
+	local := point.Copy().SubtractVector(q.Corner)
+	alpha := q.w.Dot(NewEmptyVector().VectorCrossProduct(local, q.V))
+	beta := q.w.Dot(NewEmptyVector().VectorCrossProduct(q.U, local))
+	if alpha < 0 || alpha > 1 || beta < 0 || beta > 1 {
+		return false, nil
+	}
+
+	return true, &HitRecord{
+		T:         t,
+		P:         point,
+		N:         q.Normal,
+		U:         alpha,
+		V:         beta,
+		Tangent:   q.U,
+		Bitangent: q.V,
+		Material:  q.Material,
+	}
+}
+
+func (q *Quad) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	box := NewAABB(q.Corner.Copy(), q.Corner.Copy())
+	for _, corner := range []*Vector{
+		q.Corner.Copy().AddVector(q.U),
+		q.Corner.Copy().AddVector(q.V),
+		q.Corner.Copy().AddVector(q.U).AddVector(q.V),
+	} {
+		box = SurroundingBox(box, NewAABB(corner, corner))
+	}
+	return true, box.Pad(planeBoxPadding)
+}
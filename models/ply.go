@@ -0,0 +1,284 @@
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	plyASCII              = "ascii"
+	plyBinaryLittleEndian = "binary_little_endian"
+	plyBinaryBigEndian    = "binary_big_endian"
+)
+
+type PLYModel struct {
+	Positions []*Vector
+	Normals   []*Vector
+	Colors    []*Vector
+	UVs       [][2]float64
+	Faces     [][]int
+}
+
+type plyProperty struct {
+	Name      string
+	Type      string
+	IsList    bool
+	CountType string
+}
+
+type plyElement struct {
+	Name       string
+	Count      int
+	Properties []plyProperty
+}
+
+type plyValueReader interface {
+	read(dataType string) (float64, error)
+}
+
+type plyASCIIReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *plyASCIIReader) read(dataType string) (float64, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	return strconv.ParseFloat(r.scanner.Text(), 64)
+}
+
+type plyBinaryReader struct {
+	reader *bufio.Reader
+	order  binary.ByteOrder
+	buffer [8]byte
+}
+
+func (r *plyBinaryReader) read(dataType string) (float64, error) {
+	size := plyTypeSize(dataType)
+	if size == 0 {
+		return 0, fmt.Errorf("unknown PLY property type %q", dataType)
+	}
+	b := r.buffer[:size]
+	if _, err := io.ReadFull(r.reader, b); err != nil {
+		return 0, err
+	}
+
+	switch dataType {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(r.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(r.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(r.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(r.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(r.order.Uint32(b))), nil
+	default:
+		return math.Float64frombits(r.order.Uint64(b)), nil
+	}
+}
+
+func plyTypeSize(dataType string) int {
+	switch dataType {
+	case "char", "int8", "uchar", "uint8":
+		return 1
+	case "short", "int16", "ushort", "uint16":
+		return 2
+	case "int", "int32", "uint", "uint32", "float", "float32":
+		return 4
+	case "double", "float64":
+		return 8
+	}
+	return 0
+}
+
+func LoadPLY(filePath string) (*PLYModel, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	format, elements, err := readPLYHeader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	var values plyValueReader
+	switch format {
+	case plyASCII:
+		scanner := bufio.NewScanner(reader)
+		scanner.Split(bufio.ScanWords)
+		values = &plyASCIIReader{scanner: scanner}
+	case plyBinaryLittleEndian:
+		values = &plyBinaryReader{reader: reader, order: binary.LittleEndian}
+	case plyBinaryBigEndian:
+		values = &plyBinaryReader{reader: reader, order: binary.BigEndian}
+	default:
+		return nil, fmt.Errorf("%s: unsupported PLY format %q", filePath, format)
+	}
+
+	model := &PLYModel{}
+	for _, element := range elements {
+		if err := model.readElement(element, values); err != nil {
+			return nil, fmt.Errorf("%s: element %s: %v", filePath, element.Name, err)
+		}
+	}
+
+	return model, nil
+}
+
+func readPLYHeader(reader *bufio.Reader) (string, []*plyElement, error) {
+	var format string
+	var elements []*plyElement
+
+	line, err := reader.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return "", nil, fmt.Errorf("not a PLY file")
+	}
+
+	for {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("unterminated PLY header")
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return "", nil, fmt.Errorf("invalid format line")
+			}
+			format = fields[1]
+		case "element":
+			if len(fields) < 3 {
+				return "", nil, fmt.Errorf("invalid element line")
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return "", nil, err
+			}
+			elements = append(elements, &plyElement{Name: fields[1], Count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, fmt.Errorf("property before any element")
+			}
+			element := elements[len(elements)-1]
+			if len(fields) == 5 && fields[1] == "list" {
+				element.Properties = append(element.Properties, plyProperty{
+					Name: fields[4], Type: fields[3], IsList: true, CountType: fields[2],
+				})
+			} else if len(fields) == 3 {
+				element.Properties = append(element.Properties, plyProperty{
+					Name: fields[2], Type: fields[1],
+				})
+			} else {
+				return "", nil, fmt.Errorf("invalid property line %q", strings.TrimSpace(line))
+			}
+		case "end_header":
+			return format, elements, nil
+		}
+	}
+}
+
+func (m *PLYModel) readElement(element *plyElement, values plyValueReader) error {
+	colorScale := 1.0
+	for _, property := range element.Properties {
+		if property.Name == "red" && property.Type != "float" && property.Type != "float32" &&
+			property.Type != "double" && property.Type != "float64" {
+			colorScale = 1 / 255.0
+		}
+	}
+
+	for i := 0; i < element.Count; i++ {
+		scalars := make(map[string]float64, len(element.Properties))
+		var indices []int
+
+		for _, property := range element.Properties {
+			if !property.IsList {
+				v, err := values.read(property.Type)
+				if err != nil {
+					return err
+				}
+				scalars[property.Name] = v
+				continue
+			}
+
+			count, err := values.read(property.CountType)
+			if err != nil {
+				return err
+			}
+			list := make([]int, int(count))
+			for k := range list {
+				v, err := values.read(property.Type)
+				if err != nil {
+					return err
+				}
+				list[k] = int(v)
+			}
+			if property.Name == "vertex_indices" || property.Name == "vertex_index" {
+				indices = list
+			}
+		}
+
+		switch element.Name {
+		case "vertex":
+			m.addVertex(scalars, colorScale)
+		case "face":
+			if len(indices) < 3 {
+				return fmt.Errorf("face %d has fewer than 3 vertices", i)
+			}
+			m.Faces = append(m.Faces, indices)
+		}
+	}
+
+	if element.Name == "face" {
+		for _, face := range m.Faces {
+			for _, index := range face {
+				if index < 0 || index >= len(m.Positions) {
+					return fmt.Errorf("vertex index %d out of range", index)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *PLYModel) addVertex(scalars map[string]float64, colorScale float64) {
+	m.Positions = append(m.Positions, NewVector(scalars["x"], scalars["y"], scalars["z"]))
+
+	if _, ok := scalars["nx"]; ok {
+		m.Normals = append(m.Normals, NewVector(scalars["nx"], scalars["ny"], scalars["nz"]).MakeUnitVector())
+	}
+
+	if _, ok := scalars["red"]; ok {
+		m.Colors = append(m.Colors, NewVector(scalars["red"], scalars["green"], scalars["blue"]).Scale(colorScale))
+	}
+
+	for _, names := range [][2]string{{"u", "v"}, {"s", "t"}, {"texture_u", "texture_v"}} {
+		if u, ok := scalars[names[0]]; ok {
+			m.UVs = append(m.UVs, [2]float64{u, scalars[names[1]]})
+			break
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Principled follows the Disney principled BSDF. The base colour comes from
+// Albedo and the remaining parameters lie in [0, 1]. It mixes a diffuse lobe
+// with sheen, a GGX specular lobe, a clearcoat lobe and a rough glass lobe
+// for transmission, picking one lobe at random to sample a direction and
+// weighting it by the density of all lobes together.
+type Principled struct {
+	*BaseMaterial
+	Metallic           float64
+	Roughness          float64
+	Specular           float64
+	Clearcoat          float64
+	ClearcoatRoughness float64
+	Sheen              float64
+	SheenTint          float64
+	Transmission       float64
+	RefIndex           float64
+
+	specular  ggx
+	clearcoat ggx
+	glass     *RoughDielectric
+}
+
+func NewPrincipled(baseColor Texture, metallic, roughness, specular, clearcoat, clearcoatRoughness,
+	sheen, sheenTint, transmission, refIndex float64) *Principled {
+	return &Principled{
+		BaseMaterial:       NewBaseMaterial(baseColor),
+		Metallic:           metallic,
+		Roughness:          roughness,
+		Specular:           specular,
+		Clearcoat:          clearcoat,
+		ClearcoatRoughness: clearcoatRoughness,
+		Sheen:              sheen,
+		SheenTint:          sheenTint,
+		Transmission:       transmission,
+		RefIndex:           refIndex,
+		specular:           newGGX(roughness),
+		clearcoat:          newGGX(clearcoatRoughness),
+		glass:              NewRoughDielectric(NewSolidTexture(NewVector(1, 1, 1)), refIndex, roughness),
+	}
+}
+
+// principledLobes holds the weight of each lobe for one side of the surface.
+type principledLobes struct {
+	diffuse, specular, clearcoat, glass float64
+}
+
+func (l principledLobes) total() float64 {
+	return l.diffuse + l.specular + l.clearcoat + l.glass
+}
+
+// lobes returns the lobe weights and the normal on the side of wo. Rays inside
+// a transmissive surface can only meet its glass lobe.
+func (p *Principled) lobes(wo *Vector, hitRecord *HitRecord) (principledLobes, *Vector) {
+	glass := (1 - p.Metallic) * p.Transmission
+	lobes := principledLobes{
+		diffuse:   (1 - p.Metallic) * (1 - p.Transmission),
+		specular:  1 - glass,
+		clearcoat: 0.25 * p.Clearcoat,
+		glass:     glass,
+	}
+	if wo.Dot(hitRecord.N) >= 0 {
+		return lobes, hitRecord.N
+	}
+	if glass > 0 {
+		lobes = principledLobes{glass: glass}
+	}
+	return lobes, hitRecord.N.Copy().Negate()
+}
+
+func (p *Principled) Scatter(ray *Ray, hitRecord *HitRecord, rng *rand.Rand) (bool, *Vector, *Ray) {
+	wo := ray.Direction.Copy().Negate().MakeUnitVector()
+	lobes, n := p.lobes(wo, hitRecord)
+
+	var wi *Vector
+	pick := rng.Float64() * lobes.total()
+	switch {
+	case pick < lobes.diffuse:
+		wi = RandomUnitVector(rng).AddVector(n)
+		if wi.SquaredLength() < 1e-12 {
+			wi = n.Copy()
+		}
+	case pick < lobes.diffuse+lobes.specular:
+		wi = reflectAbout(wo, p.specular.sample(n, rng))
+	case pick < lobes.diffuse+lobes.specular+lobes.clearcoat:
+		wi = reflectAbout(wo, p.clearcoat.sample(n, rng))
+	default:
+		scattered, _, glassRay := p.glass.Scatter(ray, hitRecord, rng)
+		if !scattered {
+			return false, nil, nil
+		}
+		wi = glassRay.Direction
+	}
+
+	value, pdf := p.Evaluate(ray, hitRecord, wi)
+	if pdf <= 0 {
+		return false, nil, nil
+	}
+	return true, value.Scale(1 / pdf), &Ray{hitRecord.P, wi, ray.Time, rng}
+}
+
+func reflectAbout(wo, h *Vector) *Vector {
+	return h.Copy().Scale(2 * wo.Dot(h)).SubtractVector(wo)
+}
+
+// schlickColor is Schlick's approximation of the Fresnel term with a coloured
+// reflectance f0 at normal incidence.
+func schlickColor(f0 *Vector, cos float64) *Vector {
+	w := math.Pow(1-math.Max(0, math.Min(1, cos)), 5)
+	return f0.Copy().Scale(1 - w).AddVector(NewVector(w, w, w))
+}
+
+// Evaluate returns the scattered fraction times the cosine for light arriving
+// from direction, along with the density Scatter samples that direction with.
+func (p *Principled) Evaluate(ray *Ray, hitRecord *HitRecord, direction *Vector) (*Vector, float64) {
+	wo := ray.Direction.Copy().Negate().MakeUnitVector()
+	wi := direction.Copy().MakeUnitVector()
+	lobes, n := p.lobes(wo, hitRecord)
+	total := lobes.total()
+	cosO, cosI := wo.Dot(n), wi.Dot(n)
+	if cosO <= 0 || total == 0 {
+		return NewEmptyVector(), 0
+	}
+
+	base := p.albedo(hitRecord)
+	value := NewEmptyVector()
+	var pdf float64
+
+	if lobes.glass > 0 {
+		glassValue, glassPDF := p.glass.Evaluate(ray, hitRecord, wi)
+		if cosI < 0 {
+			glassValue.MultiplyVector(base)
+		}
+		value.AddScaledVector(glassValue, lobes.glass)
+		pdf += lobes.glass / total * glassPDF
+	}
+
+	if cosI <= 0 {
+		return value, pdf
+	}
+
+	h := wo.Copy().AddVector(wi).MakeUnitVector()
+	cosH, cosD := h.Dot(n), wi.Dot(h)
+
+	if lobes.diffuse > 0 {
+		fd90 := 0.5 + 2*p.Roughness*cosD*cosD
+		fd := (1 + (fd90-1)*math.Pow(1-cosI, 5)) * (1 + (fd90-1)*math.Pow(1-cosO, 5))
+		diffuse := base.Copy().Scale(fd * cosI / math.Pi)
+
+		if p.Sheen > 0 {
+			tint := NewVector(1, 1, 1).Scale(1-p.SheenTint).AddScaledVector(base, p.SheenTint)
+			diffuse.AddScaledVector(tint, p.Sheen*math.Pow(1-cosD, 5)*cosI)
+		}
+
+		value.AddScaledVector(diffuse, lobes.diffuse)
+		pdf += lobes.diffuse / total * cosI / math.Pi
+	}
+
+	if lobes.specular > 0 {
+		dielectric := 0.08 * p.Specular
+		f0 := NewVector(dielectric, dielectric, dielectric).Scale(1-p.Metallic).AddScaledVector(base, p.Metallic)
+		d := p.specular.d(cosH)
+		g := p.specular.g1(cosO) * p.specular.g1(cosI)
+		value.AddScaledVector(schlickColor(f0, cosD), lobes.specular*d*g/(4*cosO))
+		pdf += lobes.specular / total * d * cosH / (4 * cosD)
+	}
+
+	if lobes.clearcoat > 0 {
+		d := p.clearcoat.d(cosH)
+		g := p.clearcoat.g1(cosO) * p.clearcoat.g1(cosI)
+		f := schlickColor(NewVector(0.04, 0.04, 0.04), cosD)
+		value.AddScaledVector(f, lobes.clearcoat*d*g/(4*cosO))
+		pdf += lobes.clearcoat / total * d * cosH / (4 * cosD)
+	}
+
+	return value, pdf
+}
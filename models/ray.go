@@ -1,8 +1,14 @@
 package models
 
+import "math/rand"
+
+// Ray carries the generator of the goroutine tracing it, so hitables which
+// sample along the ray, such as media, don't share one between goroutines.
 type Ray struct {
 	Origin    *Vector
 	Direction *Vector
+	Time      float64
+	Rand      *rand.Rand
 }
 
 func (r *Ray) PointAtParameter(t float64) *Vector {
@@ -0,0 +1,296 @@
+package models
+
+import "math"
+
+const (
+	SDFSphereNode       = "Sphere"
+	SDFBoxNode          = "Box"
+	SDFTorusNode        = "Torus"
+	SDFCylinderNode     = "Cylinder"
+	SDFMandelbulbNode   = "Mandelbulb"
+	SDFUnionNode        = "Union"
+	SDFIntersectionNode = "Intersection"
+	SDFDifferenceNode   = "Difference"
+	SDFSmoothUnionNode  = "SmoothUnion"
+	SDFTranslateNode    = "Translate"
+	SDFScaleNode        = "Scale"
+	SDFRepeatNode       = "Repeat"
+	SDFTwistNode        = "Twist"
+
+	sdfDefaultMaxSteps = 256
+	sdfDefaultEpsilon  = 1e-4
+)
+
+// DistanceFunction returns a signed distance from p to a surface, negative
+// inside. Implementations must not modify p.
+type DistanceFunction interface {
+	Distance(p *Vector) float64
+}
+
+type SDFSphere struct {
+	Radius float64
+}
+
+func (s *SDFSphere) Distance(p *Vector) float64 {
+	return p.Length() - s.Radius
+}
+
+// SDFBox is centered on the origin with half extents Size.
+type SDFBox struct {
+	Size *Vector
+}
+
+func (b *SDFBox) Distance(p *Vector) float64 {
+	var outside, inside float64
+	inside = -math.MaxFloat64
+	for a := 0; a < 3; a++ {
+		q := math.Abs(p.data[a]) - b.Size.data[a]
+		if q > 0 {
+			outside += q * q
+		}
+		inside = math.Max(inside, q)
+	}
+	return math.Sqrt(outside) + math.Min(inside, 0)
+}
+
+// SDFTorus lies in the XZ plane around the Y axis.
+type SDFTorus struct {
+	MajorRadius, MinorRadius float64
+}
+
+func (t *SDFTorus) Distance(p *Vector) float64 {
+	q := math.Hypot(p.data[0], p.data[2]) - t.MajorRadius
+	return math.Hypot(q, p.data[1]) - t.MinorRadius
+}
+
+// SDFCylinder is a capped cylinder around the Y axis centered on the origin.
+type SDFCylinder struct {
+	Radius, Height float64
+}
+
+func (c *SDFCylinder) Distance(p *Vector) float64 {
+	dx := math.Hypot(p.data[0], p.data[2]) - c.Radius
+	dy := math.Abs(p.data[1]) - c.Height/2
+	return math.Min(math.Max(dx, dy), 0) + math.Hypot(math.Max(dx, 0), math.Max(dy, 0))
+}
+
+// SDFMandelbulb is the distance estimate of the Mandelbulb fractal of the
+// given power, which fits inside a sphere of radius 1.2 for power 8.
+type SDFMandelbulb struct {
+	Power      float64
+	Iterations int
+}
+
+func (m *SDFMandelbulb) Distance(p *Vector) float64 {
+	x, y, z := p.data[0], p.data[1], p.data[2]
+	dr, r := 1.0, 0.0
+	for i := 0; i < m.Iterations; i++ {
+		r = math.Sqrt(x*x + y*y + z*z)
+		if r > 2 {
+			break
+		}
+		theta := math.Acos(z/r) * m.Power
+		phi := math.Atan2(y, x) * m.Power
+		dr = math.Pow(r, m.Power-1)*m.Power*dr + 1
+
+		zr := math.Pow(r, m.Power)
+		sinTheta, cosTheta := math.Sincos(theta)
+		sinPhi, cosPhi := math.Sincos(phi)
+		x = zr*sinTheta*cosPhi + p.data[0]
+		y = zr*sinTheta*sinPhi + p.data[1]
+		z = zr*cosTheta + p.data[2]
+	}
+	if r == 0 {
+		return 0
+	}
+	return 0.5 * math.Log(r) * r / dr
+}
+
+type SDFUnion struct {
+	Children []DistanceFunction
+}
+
+func (u *SDFUnion) Distance(p *Vector) float64 {
+	d := math.MaxFloat64
+	for _, child := range u.Children {
+		d = math.Min(d, child.Distance(p))
+	}
+	return d
+}
+
+type SDFIntersection struct {
+	Children []DistanceFunction
+}
+
+func (in *SDFIntersection) Distance(p *Vector) float64 {
+	d := -math.MaxFloat64
+	for _, child := range in.Children {
+		d = math.Max(d, child.Distance(p))
+	}
+	return d
+}
+
+// SDFDifference removes every child after the first from the first child.
+type SDFDifference struct {
+	Children []DistanceFunction
+}
+
+func (df *SDFDifference) Distance(p *Vector) float64 {
+	d := df.Children[0].Distance(p)
+	for _, child := range df.Children[1:] {
+		d = math.Max(d, -child.Distance(p))
+	}
+	return d
+}
+
+// SDFSmoothUnion blends its children with a polynomial smooth minimum, where
+// Smoothness is roughly the distance over which surfaces blend.
+type SDFSmoothUnion struct {
+	Children   []DistanceFunction
+	Smoothness float64
+}
+
+func (s *SDFSmoothUnion) Distance(p *Vector) float64 {
+	d := s.Children[0].Distance(p)
+	for _, child := range s.Children[1:] {
+		d = smoothMin(d, child.Distance(p), s.Smoothness)
+	}
+	return d
+}
+
+func smoothMin(a, b, k float64) float64 {
+	if k <= 0 {
+		return math.Min(a, b)
+	}
+	h := math.Max(k-math.Abs(a-b), 0) / k
+	return math.Min(a, b) - h*h*k/4
+}
+
+type SDFTranslate struct {
+	Offset *Vector
+	Child  DistanceFunction
+}
+
+func (t *SDFTranslate) Distance(p *Vector) float64 {
+	return t.Child.Distance(p.Copy().SubtractVector(t.Offset))
+}
+
+type SDFScale struct {
+	Factor float64
+	Child  DistanceFunction
+}
+
+func (s *SDFScale) Distance(p *Vector) float64 {
+	return s.Child.Distance(p.Copy().Scale(1/s.Factor)) * s.Factor
+}
+
+// SDFRepeat tiles space with cells of size Period centered on the origin. A
+// zero period leaves that axis alone.
+type SDFRepeat struct {
+	Period *Vector
+	Child  DistanceFunction
+}
+
+func (r *SDFRepeat) Distance(p *Vector) float64 {
+	q := p.Copy()
+	for a := 0; a < 3; a++ {
+		if period := r.Period.data[a]; period > 0 {
+			q.data[a] -= period * math.Floor(q.data[a]/period+0.5)
+		}
+	}
+	return r.Child.Distance(q)
+}
+
+// SDFTwist rotates the XZ plane by Amount radians per unit along Y. Twisting
+// stretches distances, so objects using it need a StepScale below 1.
+type SDFTwist struct {
+	Amount float64
+	Child  DistanceFunction
+}
+
+func (t *SDFTwist) Distance(p *Vector) float64 {
+	sin, cos := math.Sincos(t.Amount * p.data[1])
+	q := NewVector(cos*p.data[0]-sin*p.data[2], p.data[1], sin*p.data[0]+cos*p.data[2])
+	return t.Child.Distance(q)
+}
+
+// SDF renders a distance function by sphere tracing inside Box.
+type SDF struct {
+	Function  DistanceFunction
+	Box       *AABB
+	Material  Material
+	MaxSteps  int
+	Epsilon   float64
+	StepScale float64
+}
+
+func NewSDF(function DistanceFunction, box *AABB, material Material) *SDF {
+	return &SDF{
+		Function:  function,
+		Box:       box,
+		Material:  material,
+		MaxSteps:  sdfDefaultMaxSteps,
+		Epsilon:   sdfDefaultEpsilon,
+		StepScale: 1,
+	}
+}
+
+func (s *SDF) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	hit, t0, t1 := s.Box.Clip(r, tmin, tmax)
+	if !hit {
+		return false, nil
+	}
+
+	length := r.Direction.Length()
+	t := t0
+	d := s.Function.Distance(r.PointAtParameter(t))
+
+	// A ray leaving the surface starts within the surface tolerance. Step out
+	// of that shell first so the ray does not find its own origin.
+	for k := 0; k < 16 && math.Abs(d) < 2*s.Epsilon && t < t1; k++ {
+		t += 2 * s.Epsilon / length
+		d = s.Function.Distance(r.PointAtParameter(t))
+	}
+
+	sign := 1.0
+	if d < 0 {
+		sign = -1
+	}
+
+	for i := 0; i < s.MaxSteps && t < t1; i++ {
+		d = sign * s.Function.Distance(r.PointAtParameter(t))
+		if d < s.Epsilon {
+			p := r.PointAtParameter(t)
+			n := s.normal(p)
+			u, v := SphereUV(n)
+			return true, &HitRecord{
+				T:        t,
+				P:        p,
+				N:        n,
+				U:        u,
+				V:        v,
+				Material: s.Material,
+			}
+		}
+		t += d * s.StepScale / length
+	}
+
+	return false, nil
+}
+
+// normal estimates the gradient of the distance function by sampling the
+// four corners of a small tetrahedron around p.
+func (s *SDF) normal(p *Vector) *Vector {
+	h := s.Epsilon
+	n := NewEmptyVector()
+	for _, k := range [4][3]float64{{1, -1, -1}, {-1, -1, 1}, {-1, 1, -1}, {1, 1, 1}} {
+		offset := NewVector(k[0], k[1], k[2])
+		d := s.Function.Distance(p.Copy().AddScaledVector(offset, h))
+		n.AddScaledVector(offset, d)
+	}
+	return n.MakeUnitVector()
+}
+
+func (s *SDF) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	return true, s.Box
+}
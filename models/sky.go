@@ -0,0 +1,150 @@
+package models
+
+import "math"
+
+// skyUnit brings daylight luminance, in thousands of cd/m², to the range of
+// the renderer's lights, where 1 is a white Light surface.
+const skyUnit = 1.0 / 20
+
+// skyMapWidth and skyMapHeight set the resolution the sky is baked at for
+// rendering and sampling.
+const (
+	skyMapWidth  = 512
+	skyMapHeight = 256
+)
+
+// Sky is the clear sky model of Preetham, Shirley and Smits, with the sun in
+// SunDirection. Turbidity measures the haze in the air, from 2 for a very
+// clear sky to 10 for a hazy one. Below the horizon lies a flat diffuse
+// ground of colour GroundAlbedo lit by the sky and the sun.
+type Sky struct {
+	SunDirection *Vector
+	Turbidity    float64
+	GroundAlbedo *Vector
+
+	sunTheta float64
+	zenith   [3]float64
+	perez    [3][5]float64
+}
+
+func NewSky(sunDirection *Vector, turbidity float64, groundAlbedo *Vector) *Sky {
+	s := &Sky{
+		SunDirection: sunDirection.Copy().MakeUnitVector(),
+		Turbidity:    turbidity,
+		GroundAlbedo: groundAlbedo,
+	}
+
+	// The model only holds for the sun above the horizon.
+	s.sunTheta = math.Min(math.Acos(math.Max(-1, math.Min(1, s.SunDirection.data[1]))), math.Pi/2)
+	t, theta := turbidity, s.sunTheta
+	theta2, theta3 := theta*theta, theta*theta*theta
+
+	chi := (4.0/9 - t/120) * (math.Pi - 2*theta)
+	s.zenith[0] = ((4.0453*t-4.9710)*math.Tan(chi) - 0.2155*t + 2.4192)
+	s.zenith[1] = t*t*(0.00166*theta3-0.00375*theta2+0.00209*theta) +
+		t*(-0.02903*theta3+0.06377*theta2-0.03202*theta+0.00394) +
+		(0.11693*theta3 - 0.21196*theta2 + 0.06052*theta + 0.25886)
+	s.zenith[2] = t*t*(0.00275*theta3-0.00610*theta2+0.00317*theta) +
+		t*(-0.04214*theta3+0.08970*theta2-0.04153*theta+0.00516) +
+		(0.15346*theta3 - 0.26756*theta2 + 0.06670*theta + 0.26688)
+
+	s.perez = [3][5]float64{
+		{0.1787*t - 1.4630, -0.3554*t + 0.4275, -0.0227*t + 5.3251, 0.1206*t - 2.5771, -0.0670*t + 0.3703},
+		{-0.0193*t - 0.2592, -0.0665*t + 0.0008, -0.0004*t + 0.2125, -0.0641*t - 0.8989, -0.0033*t + 0.0452},
+		{-0.0167*t - 0.2608, -0.0950*t + 0.0092, -0.0079*t + 0.2102, -0.0441*t - 1.6537, -0.0109*t + 0.0529},
+	}
+	return s
+}
+
+// perezValue is the Perez distribution for view angle theta from the zenith
+// and angle gamma from the sun.
+func perezValue(c [5]float64, theta, gamma float64) float64 {
+	cosTheta := math.Max(math.Cos(theta), 0.01)
+	cosGamma := math.Cos(gamma)
+	return (1 + c[0]*math.Exp(c[1]/cosTheta)) * (1 + c[2]*math.Exp(c[3]*gamma) + c[4]*cosGamma*cosGamma)
+}
+
+// SkyRadiance returns the light of the sky arriving along the reverse of a
+// unit direction above the horizon.
+func (s *Sky) SkyRadiance(direction *Vector) *Vector {
+	theta := math.Acos(math.Max(-1, math.Min(1, direction.data[1])))
+	gamma := math.Acos(math.Max(-1, math.Min(1, direction.Dot(s.SunDirection))))
+
+	var xyY [3]float64
+	for k := range xyY {
+		xyY[k] = s.zenith[k] * perezValue(s.perez[k], theta, gamma) / perezValue(s.perez[k], 0, s.sunTheta)
+	}
+	return xyYToRGB(xyY[1], xyY[2], xyY[0]).Scale(skyUnit)
+}
+
+// xyYToRGB converts a CIE xyY colour to linear sRGB, dropping negative
+// components outside the gamut.
+func xyYToRGB(x, y, luminance float64) *Vector {
+	if y <= 0 {
+		return NewEmptyVector()
+	}
+	X, Z := x/y*luminance, (1-x-y)/y*luminance
+	Y := luminance
+	return NewVector(
+		math.Max(0, 3.2406*X-1.5372*Y-0.4986*Z),
+		math.Max(0, -0.9689*X+1.8758*Y+0.0415*Z),
+		math.Max(0, 0.0557*X-0.2040*Y+1.0570*Z),
+	)
+}
+
+// SunIrradiance returns the light of the sun falling on a surface facing it,
+// after it passes through the air by Rayleigh and aerosol scattering.
+func (s *Sky) SunIrradiance() *Vector {
+	if s.SunDirection.data[1] <= 0 {
+		return NewEmptyVector()
+	}
+
+	// Relative optical mass of the air towards the sun.
+	degrees := s.sunTheta * 180 / math.Pi
+	mass := 1 / (math.Cos(s.sunTheta) + 0.15*math.Pow(93.885-degrees, -1.253))
+	beta := 0.04608*s.Turbidity - 0.04586
+
+	irradiance := NewEmptyVector()
+	// Wavelengths in micrometres standing for red, green and blue.
+	for k, lambda := range [3]float64{0.680, 0.550, 0.440} {
+		rayleigh := math.Exp(-0.008735 * math.Pow(lambda, -4.08) * mass)
+		aerosol := math.Exp(-beta * math.Pow(lambda, -1.3) * mass)
+		irradiance.data[k] = 128 * rayleigh * aerosol
+	}
+	return irradiance.Scale(skyUnit)
+}
+
+// Sun returns a directional light matching the sun of the sky.
+func (s *Sky) Sun(intensity float64) *Directional {
+	return NewDirectional(s.SunDirection.Copy().Negate(), s.SunIrradiance().Scale(intensity))
+}
+
+// Environment bakes the sky and ground into an environment map.
+func (s *Sky) Environment(intensity float64) *Environment {
+	sky := &Image{
+		Width:  skyMapWidth,
+		Height: skyMapHeight,
+		Pixels: make([]*Vector, skyMapWidth*skyMapHeight),
+	}
+	unrotated := &Environment{cos: 1}
+
+	// Light falling on the ground from the sky, summed over the pixels of the
+	// upper half of the map, and from the sun.
+	groundIrradiance := s.SunIrradiance().Scale(math.Max(0, s.SunDirection.data[1]))
+	for j := 0; j < skyMapHeight/2; j++ {
+		v := (float64(j) + 0.5) / skyMapHeight
+		solidAngle := 2 * math.Pi * math.Pi * math.Sin(math.Pi*v) / (skyMapWidth * skyMapHeight)
+		for i := 0; i < skyMapWidth; i++ {
+			direction := unrotated.direction((float64(i)+0.5)/skyMapWidth, v)
+			radiance := s.SkyRadiance(direction)
+			sky.Pixels[j*skyMapWidth+i] = radiance
+			groundIrradiance.AddScaledVector(radiance, direction.data[1]*solidAngle)
+		}
+	}
+
+	ground := groundIrradiance.MultiplyVector(s.GroundAlbedo).Scale(1 / math.Pi)
+	for k := skyMapHeight / 2 * skyMapWidth; k < len(sky.Pixels); k++ {
+		sky.Pixels[k] = ground.Copy()
+	}
+	return NewEnvironment(sky, 0, intensity)
+}
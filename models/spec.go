@@ -1,6 +1,10 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
 
 type ObjectType int
 
@@ -9,14 +13,34 @@ const (
 	MetalMaterial      = "Metal"
 	DielectricMaterial = "Dielectric"
 	LightMaterial      = "Light"
+	IsotropicMaterial  = "Isotropic"
+	ConductorMaterial  = "Conductor"
+	RoughGlassMaterial = "RoughDielectric"
+	PrincipledMaterial = "Principled"
+)
+
+const (
+	BVHAuto = "Auto"
+	BVHOn   = "On"
+	BVHOff  = "Off"
+
+	BVHThreshold = 16
 )
 
 type ImageInput struct {
-	OutputFile string
-	Height     int
-	Width      int
-	Samples    int
-	Patch      [4]int
+	OutputFile  string
+	Format      string
+	PixelType   string
+	Compression string
+	ToneMapping string
+	Exposure    float64
+	WhitePoint  float64
+	Transfer    string
+	AOVs        []string
+	Height      int
+	Width       int
+	Samples     int
+	Patch       [4]int
 }
 
 func (i *ImageInput) GetPatch() (int, int, int, int) {
@@ -24,27 +48,113 @@ func (i *ImageInput) GetPatch() (int, int, int, int) {
 }
 
 type CameraInput struct {
-	LookFrom    [3]float64
-	LookAt      [3]float64
-	UpVector    [3]float64
-	FieldOfView float64
-	AspectRatio float64
-	Focus       float64
-	Aperture    float64
+	LookFrom     [3]float64
+	LookAt       [3]float64
+	UpVector     [3]float64
+	FieldOfView  float64
+	AspectRatio  float64
+	Focus        float64
+	Aperture     float64
+	ShutterOpen  float64
+	ShutterClose float64
+}
+
+// TextureInput describes a texture by Type. Checker uses Odd, Even, Size and
+// UV, Image uses File, and the Perlin noise textures use Color, Scale, Depth
+// and Seed.
+type TextureInput struct {
+	Type      string
+	Color     [3]float64
+	Odd, Even *TextureInput
+	Size      float64
+	UV        bool
+	File      string
+	Scale     float64
+	Depth     int
+	Seed      int64
+}
+
+func (t *TextureInput) getTexture() Texture {
+	color := NewVectorFromArray(t.Color)
+	if t.Type != ConstantTexture && t.Color == [3]float64{} {
+		color = NewVector(1, 1, 1)
+	}
+	scale := t.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	depth := t.Depth
+	if depth == 0 {
+		depth = 7
+	}
+
+	switch t.Type {
+	case ConstantTexture:
+		return NewSolidTexture(color)
+	case CheckerTexture:
+		if t.Odd == nil || t.Even == nil {
+			panic("Checker texture needs Odd and Even textures")
+		}
+		size := t.Size
+		if size == 0 {
+			size = 1
+		}
+		return NewChecker(t.Odd.getTexture(), t.Even.getTexture(), size, t.UV)
+	case ImageTexture:
+		image, err := LoadImage(t.File)
+		if err != nil {
+			panic(fmt.Sprintf("Unable to load texture: %v", err))
+		}
+		return image
+	case NoiseTexture:
+		return &Noise{Perlin: NewPerlin(t.Seed), Scale: scale, Color: color}
+	case TurbulenceTexture:
+		return &Turbulence{Perlin: NewPerlin(t.Seed), Scale: scale, Depth: depth, Color: color}
+	case MarbleTexture:
+		return &Marble{Perlin: NewPerlin(t.Seed), Scale: scale, Depth: depth, Color: color}
+	}
+
+	panic(fmt.Sprintf("Got invalid texture type: %s", t.Type))
 }
 
 type SurfaceInput struct {
-	Type     string
-	Albedo   [3]float64
-	Fuzz     float64
-	RefIndex float64
+	Type         string
+	Albedo       [3]float64
+	Texture      *TextureInput
+	Fuzz         float64
+	RefIndex     float64
+	Roughness    float64
+	Preset       string
+	Eta          [3]float64
+	K            [3]float64
+	VertexColors bool
+
+	Metallic           float64
+	Specular           *float64
+	Clearcoat          float64
+	ClearcoatRoughness float64
+	Sheen              float64
+	SheenTint          float64
+	Transmission       float64
+
+	NormalMap string
+	BumpMap   string
+	BumpScale float64
+
+	Emission         [3]float64
+	EmissionTexture  *TextureInput
+	EmissionStrength float64
+	OneSided         bool
 }
 
 func (s *SurfaceInput) getMaterial() Material {
 
-	var material Material
+	var material baseMaterial
 
-	albedo := NewVectorFromArray(s.Albedo)
+	var albedo Texture = NewSolidTexture(NewVectorFromArray(s.Albedo))
+	if s.Texture != nil {
+		albedo = s.Texture.getTexture()
+	}
 	switch s.Type {
 	case LambertianMaterial:
 		material = NewLambertian(albedo)
@@ -54,10 +164,73 @@ func (s *SurfaceInput) getMaterial() Material {
 		material = NewDielectric(albedo, s.RefIndex)
 	case LightMaterial:
 		material = NewLight(albedo)
+	case IsotropicMaterial:
+		material = NewIsotropic(albedo)
+	case ConductorMaterial:
+		if s.Albedo == [3]float64{} && s.Texture == nil {
+			albedo = NewSolidTexture(NewVector(1, 1, 1))
+		}
+		eta, k := s.Eta, s.K
+		if s.Preset != "" {
+			preset, ok := ConductorPresets[s.Preset]
+			if !ok {
+				panic(fmt.Sprintf("Got invalid conductor preset: %s", s.Preset))
+			}
+			eta, k = preset[0], preset[1]
+		}
+		material = NewConductor(albedo, s.Roughness, NewVectorFromArray(eta), NewVectorFromArray(k))
+	case RoughGlassMaterial:
+		material = NewRoughDielectric(albedo, s.RefIndex, s.Roughness)
+	case PrincipledMaterial:
+		specular := 0.5
+		if s.Specular != nil {
+			specular = *s.Specular
+		}
+		refIndex := s.RefIndex
+		if refIndex == 0 {
+			refIndex = 1.5
+		}
+		material = NewPrincipled(albedo, s.Metallic, s.Roughness, specular, s.Clearcoat, s.ClearcoatRoughness,
+			s.Sheen, s.SheenTint, s.Transmission, refIndex)
 	default:
 		panic(fmt.Sprintf("Got invalid surface type: %s", s.Type))
 	}
 
+	base := material.base()
+	base.VertexColors = s.VertexColors
+	base.OneSided = s.OneSided
+	base.ID = s.id()
+
+	if s.EmissionTexture != nil {
+		base.Emission = s.EmissionTexture.getTexture()
+	} else if s.Emission != [3]float64{} {
+		base.Emission = NewSolidTexture(NewVectorFromArray(s.Emission))
+	}
+	base.EmissionStrength = 1
+	if s.EmissionStrength > 0 {
+		base.EmissionStrength = s.EmissionStrength
+	}
+
+	if s.NormalMap != "" {
+		normalMap, err := LoadImageData(s.NormalMap)
+		if err != nil {
+			panic(fmt.Sprintf("Unable to load normal map: %v", err))
+		}
+		base.NormalMap = normalMap
+	}
+
+	if s.BumpMap != "" {
+		bumpMap, err := LoadImageData(s.BumpMap)
+		if err != nil {
+			panic(fmt.Sprintf("Unable to load bump map: %v", err))
+		}
+		base.BumpMap = bumpMap
+		base.BumpScale = s.BumpScale
+		if base.BumpScale == 0 {
+			base.BumpScale = 1
+		}
+	}
+
 	return material
 }
 
@@ -71,19 +244,534 @@ func (s SphereInput) getSphere() *Sphere {
 	return NewSphere(s.Center[0], s.Center[1], s.Center[2], s.Radius, s.Surface.getMaterial())
 }
 
+type SphereKeyframeInput struct {
+	Time   float64
+	Center [3]float64
+}
+
+type MovingSphereInput struct {
+	Keyframes []SphereKeyframeInput
+	Radius    float64
+	Surface   SurfaceInput
+}
+
+func (m MovingSphereInput) getMovingSphere() *MovingSphere {
+	if len(m.Keyframes) == 0 {
+		panic("Moving sphere needs at least one keyframe")
+	}
+
+	keyframes := make([]SphereKeyframe, len(m.Keyframes))
+	for i, keyframe := range m.Keyframes {
+		if i > 0 && keyframe.Time <= m.Keyframes[i-1].Time {
+			panic("Moving sphere keyframes must be in increasing order of time")
+		}
+		keyframes[i] = SphereKeyframe{
+			Time:   keyframe.Time,
+			Center: NewVectorFromArray(keyframe.Center),
+		}
+	}
+
+	return NewMovingSphere(keyframes, m.Radius, m.Surface.getMaterial())
+}
+
+type PlaneInput struct {
+	Point   [3]float64
+	Normal  [3]float64
+	Surface SurfaceInput
+}
+
+func (p PlaneInput) getPlane() *Plane {
+	return NewPlane(NewVectorFromArray(p.Point), NewVectorFromArray(p.Normal), p.Surface.getMaterial())
+}
+
+type DiskInput struct {
+	Center  [3]float64
+	Normal  [3]float64
+	Radius  float64
+	Surface SurfaceInput
+}
+
+func (d DiskInput) getDisk() *Disk {
+	if d.Radius <= 0 {
+		panic("Disk radius must be positive")
+	}
+	if d.Normal == [3]float64{} {
+		panic("Disk normal must not be zero")
+	}
+	return NewDisk(NewVectorFromArray(d.Center), NewVectorFromArray(d.Normal), d.Radius, d.Surface.getMaterial())
+}
+
+type QuadInput struct {
+	Corner  [3]float64
+	U       [3]float64
+	V       [3]float64
+	Surface SurfaceInput
+}
+
+func (q QuadInput) getQuad() *Quad {
+	u, v := NewVectorFromArray(q.U), NewVectorFromArray(q.V)
+	if NewEmptyVector().VectorCrossProduct(u, v).SquaredLength() == 0 {
+		panic("Quad edges U and V must not be parallel")
+	}
+	return NewQuad(NewVectorFromArray(q.Corner), u, v, q.Surface.getMaterial())
+}
+
+type RectInput struct {
+	Plane   string
+	Min     [2]float64
+	Max     [2]float64
+	Offset  float64
+	Surface SurfaceInput
+}
+
+func (r RectInput) getQuad() *Quad {
+	if r.Min[0] == r.Max[0] || r.Min[1] == r.Max[1] {
+		panic("Rectangle must not have zero width or height")
+	}
+	quad := NewAxisAlignedQuad(r.Plane, r.Min, r.Max, r.Offset, r.Surface.getMaterial())
+	if quad == nil {
+		panic(fmt.Sprintf("Got invalid rectangle plane: %s", r.Plane))
+	}
+	return quad
+}
+
+type BoxInput struct {
+	Min     [3]float64
+	Max     [3]float64
+	Surface SurfaceInput
+}
+
+func (b BoxInput) getBox() *Box {
+	return NewBox(NewVectorFromArray(b.Min), NewVectorFromArray(b.Max), b.Surface.getMaterial())
+}
+
+type CylinderInput struct {
+	Base    [3]float64
+	Top     [3]float64
+	Radius  float64
+	Capped  bool
+	Surface SurfaceInput
+}
+
+func (c CylinderInput) getCylinder() *Cylinder {
+	return NewCylinder(NewVectorFromArray(c.Base), NewVectorFromArray(c.Top), c.Radius, c.Capped, c.Surface.getMaterial())
+}
+
+type ConeInput struct {
+	Base    [3]float64
+	Apex    [3]float64
+	Radius  float64
+	Capped  bool
+	Surface SurfaceInput
+}
+
+func (c ConeInput) getCone() *Cone {
+	return NewCone(NewVectorFromArray(c.Base), NewVectorFromArray(c.Apex), c.Radius, c.Capped, c.Surface.getMaterial())
+}
+
+type TorusInput struct {
+	Center      [3]float64
+	Axis        [3]float64
+	MajorRadius float64
+	MinorRadius float64
+	Surface     SurfaceInput
+}
+
+func (t TorusInput) getTorus() *Torus {
+	return NewTorus(NewVectorFromArray(t.Center), NewVectorFromArray(t.Axis), t.MajorRadius, t.MinorRadius, t.Surface.getMaterial())
+}
+
+// HeightfieldInput spreads a grayscale PNG over Extent along X and Z from
+// Min, with white pixels Height above Min.
+type HeightfieldInput struct {
+	File    string
+	Min     [3]float64
+	Extent  [2]float64
+	Height  float64
+	Surface SurfaceInput
+}
+
+func (h HeightfieldInput) getHeightfield() *Heightfield {
+	heights, columns, rows, err := LoadHeightmap(h.File)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to load heightmap: %v", err))
+	}
+
+	size := NewVector(h.Extent[0], h.Height, h.Extent[1])
+	return NewHeightfield(heights, columns, rows, NewVectorFromArray(h.Min), size, h.Surface.getMaterial())
+}
+
+type TransformInput struct {
+	Position [3]float64
+	Scale    [3]float64
+	Rotation [3]float64
+	Matrix   []float64
+}
+
+// getMatrix uses Matrix, in row major order, when given. Otherwise the object
+// is scaled, rotated about X, Y and Z (in degrees, in that order) and then
+// translated to Position.
+func (t TransformInput) getMatrix() *Matrix4 {
+	if t.Matrix != nil {
+		if len(t.Matrix) != 16 {
+			panic(fmt.Sprintf("Transform matrix needs 16 values, got %d", len(t.Matrix)))
+		}
+		return NewMatrixFromSlice(t.Matrix)
+	}
+
+	scale := t.Scale
+	if scale == [3]float64{} {
+		scale = [3]float64{1, 1, 1}
+	}
+
+	m := ScalingMatrix(scale[0], scale[1], scale[2])
+	for axis, degrees := range t.Rotation {
+		if degrees != 0 {
+			m = RotationMatrix(axis, degrees).Multiply(m)
+		}
+	}
+	return TranslationMatrix(t.Position[0], t.Position[1], t.Position[2]).Multiply(m)
+}
+
+type MeshInput struct {
+	File string
+	TransformInput
+	Surface   SurfaceInput
+	Materials map[string]SurfaceInput
+}
+
+func (m MeshInput) getMesh() *TriangleMesh {
+	transform := m.getMatrix()
+
+	switch strings.ToLower(filepath.Ext(m.File)) {
+	case ".obj":
+		return m.getOBJMesh(transform)
+	case ".ply":
+		return m.getPLYMesh(transform)
+	default:
+		panic(fmt.Sprintf("Got unsupported mesh file: %s", m.File))
+	}
+}
+
+func (m MeshInput) getOBJMesh(transform *Matrix4) *TriangleMesh {
+	model, err := LoadOBJ(m.File)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to load mesh: %v", err))
+	}
+	if len(model.Faces) == 0 {
+		panic(fmt.Sprintf("Mesh %s has no faces", m.File))
+	}
+
+	var defaultMaterial Material
+	groupMaterials := make(map[string]Material)
+	for group, surface := range m.Materials {
+		groupMaterials[group] = surface.getMaterial()
+	}
+
+	return NewTriangleMeshFromOBJ(model, transform, func(group string) Material {
+		if material, ok := groupMaterials[group]; ok {
+			return material
+		}
+		if defaultMaterial == nil {
+			defaultMaterial = m.Surface.getMaterial()
+		}
+		return defaultMaterial
+	})
+}
+
+func (m MeshInput) getPLYMesh(transform *Matrix4) *TriangleMesh {
+	model, err := LoadPLY(m.File)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to load mesh: %v", err))
+	}
+	if len(model.Faces) == 0 {
+		panic(fmt.Sprintf("Mesh %s has no faces", m.File))
+	}
+
+	return NewTriangleMeshFromPLY(model, transform, m.Surface.getMaterial())
+}
+
+type InstanceInput struct {
+	Prototype string
+	TransformInput
+}
+
+type CSGInput struct {
+	Operation string
+	Left      ObjectsInput
+	Right     ObjectsInput
+}
+
+func (c CSGInput) getCSG(prototypes *prototypeBuilder) *CSG {
+	switch c.Operation {
+	case CSGUnion, CSGIntersection, CSGDifference:
+	default:
+		panic(fmt.Sprintf("Got invalid CSG operation: %s", c.Operation))
+	}
+
+	return NewCSG(c.Operation, getSolid(c.Left, prototypes), getSolid(c.Right, prototypes), prototypes.shutterOpen, prototypes.shutterClose)
+}
+
+// getSolid merges all objects of an input into one closed solid. Objects may
+// overlap, so they are combined with unions rather than a plain list.
+func getSolid(input ObjectsInput, prototypes *prototypeBuilder) Hitable {
+	objects := input.getHitables(prototypes)
+	if len(objects) == 0 {
+		panic("Solid has no objects")
+	}
+
+	operand := objects[0]
+	for _, object := range objects[1:] {
+		operand = NewCSG(CSGUnion, operand, object, prototypes.shutterOpen, prototypes.shutterClose)
+	}
+	return operand
+}
+
+type MediumInput struct {
+	Boundary ObjectsInput
+	Density  float64
+	Albedo   [3]float64
+}
+
+func (m MediumInput) getMedium(prototypes *prototypeBuilder) *ConstantMedium {
+	return NewConstantMedium(getSolid(m.Boundary, prototypes), m.Density, NewVectorFromArray(m.Albedo))
+}
+
+// SDFNodeInput describes one node of a distance function tree. Shapes use
+// the fields named after them, operations combine Children and transforms
+// apply to their single child.
+type SDFNodeInput struct {
+	Type        string
+	Radius      float64
+	Size        [3]float64
+	MajorRadius float64
+	MinorRadius float64
+	Height      float64
+	Power       float64
+	Iterations  int
+	Smoothness  float64
+	Offset      [3]float64
+	Factor      float64
+	Period      [3]float64
+	Amount      float64
+	Children    []SDFNodeInput
+}
+
+func (n SDFNodeInput) getDistanceFunction() DistanceFunction {
+	var children []DistanceFunction
+	for _, child := range n.Children {
+		children = append(children, child.getDistanceFunction())
+	}
+
+	switch n.Type {
+	case SDFSphereNode:
+		return &SDFSphere{Radius: n.Radius}
+	case SDFBoxNode:
+		return &SDFBox{Size: NewVectorFromArray(n.Size)}
+	case SDFTorusNode:
+		return &SDFTorus{MajorRadius: n.MajorRadius, MinorRadius: n.MinorRadius}
+	case SDFCylinderNode:
+		return &SDFCylinder{Radius: n.Radius, Height: n.Height}
+	case SDFMandelbulbNode:
+		power, iterations := n.Power, n.Iterations
+		if power == 0 {
+			power = 8
+		}
+		if iterations == 0 {
+			iterations = 12
+		}
+		return &SDFMandelbulb{Power: power, Iterations: iterations}
+	}
+
+	if len(children) == 0 {
+		panic(fmt.Sprintf("SDF node %s has no children", n.Type))
+	}
+
+	switch n.Type {
+	case SDFUnionNode:
+		return &SDFUnion{Children: children}
+	case SDFIntersectionNode:
+		return &SDFIntersection{Children: children}
+	case SDFDifferenceNode:
+		return &SDFDifference{Children: children}
+	case SDFSmoothUnionNode:
+		return &SDFSmoothUnion{Children: children, Smoothness: n.Smoothness}
+	}
+
+	if len(children) != 1 {
+		panic(fmt.Sprintf("SDF node %s needs exactly one child, got %d", n.Type, len(children)))
+	}
+
+	switch n.Type {
+	case SDFTranslateNode:
+		return &SDFTranslate{Offset: NewVectorFromArray(n.Offset), Child: children[0]}
+	case SDFScaleNode:
+		if n.Factor <= 0 {
+			panic("SDF scale factor must be positive")
+		}
+		return &SDFScale{Factor: n.Factor, Child: children[0]}
+	case SDFRepeatNode:
+		return &SDFRepeat{Period: NewVectorFromArray(n.Period), Child: children[0]}
+	case SDFTwistNode:
+		return &SDFTwist{Amount: n.Amount, Child: children[0]}
+	}
+
+	panic(fmt.Sprintf("Got invalid SDF node type: %s", n.Type))
+}
+
+// SDFInput needs Min and Max to bound the surface, since marching only
+// happens inside that box.
+type SDFInput struct {
+	Shape     SDFNodeInput
+	Min       [3]float64
+	Max       [3]float64
+	Surface   SurfaceInput
+	StepScale float64
+	MaxSteps  int
+	Epsilon   float64
+}
+
+func (s SDFInput) getSDF() *SDF {
+	box := NewAABB(NewVectorFromArray(s.Min), NewVectorFromArray(s.Max))
+	sdf := NewSDF(s.Shape.getDistanceFunction(), box, s.Surface.getMaterial())
+	if s.StepScale > 0 {
+		sdf.StepScale = s.StepScale
+	}
+	if s.MaxSteps > 0 {
+		sdf.MaxSteps = s.MaxSteps
+	}
+	if s.Epsilon > 0 {
+		sdf.Epsilon = s.Epsilon
+	}
+	return sdf
+}
+
 type ObjectsInput struct {
-	Spheres []SphereInput
+	Spheres       []SphereInput
+	MovingSpheres []MovingSphereInput
+	Meshes        []MeshInput
+	Planes        []PlaneInput
+	Disks         []DiskInput
+	Quads         []QuadInput
+	Rects         []RectInput
+	Boxes         []BoxInput
+	Cylinders     []CylinderInput
+	Cones         []ConeInput
+	Tori          []TorusInput
+	Instances     []InstanceInput
+	CSG           []CSGInput
+	Media         []MediumInput
+	SDFs          []SDFInput
+	Heightfields  []HeightfieldInput
 }
 
 type Setting struct {
 	RenderRoutines int
 	RenderDepth    int
+	BVH            string
+}
+
+func (s *Setting) useBVH(objectCount int) bool {
+	switch s.BVH {
+	case BVHOn:
+		return true
+	case BVHOff:
+		return false
+	case "", BVHAuto:
+		return objectCount > BVHThreshold
+	default:
+		panic(fmt.Sprintf("Got invalid BVH setting: %s", s.BVH))
+	}
+}
+
+const DefaultFogDistance = 100
+
+type FogInput struct {
+	Density  float64
+	Albedo   [3]float64
+	Distance float64
+}
+
+type LightInput struct {
+	Type       string
+	Position   [3]float64
+	Direction  [3]float64
+	Color      [3]float64
+	Intensity  float64
+	InnerAngle float64
+	OuterAngle float64
+	Falloff    float64
+}
+
+func (l LightInput) getLight() DeltaLight {
+	intensity := l.Intensity
+	if intensity == 0 {
+		intensity = 1
+	}
+	color := NewVectorFromArray(l.Color)
+	if l.Color == [3]float64{} {
+		color = NewVector(1, 1, 1)
+	}
+	color.Scale(intensity)
+
+	switch l.Type {
+	case PointLight:
+		return NewPoint(NewVectorFromArray(l.Position), color)
+	case SpotLight:
+		falloff := l.Falloff
+		if falloff == 0 {
+			falloff = 1
+		}
+		if l.InnerAngle > l.OuterAngle {
+			panic("Spot light InnerAngle must not be greater than OuterAngle")
+		}
+		return NewSpot(NewVectorFromArray(l.Position), NewVectorFromArray(l.Direction), color,
+			l.InnerAngle, l.OuterAngle, falloff)
+	case DirectionalLight:
+		return NewDirectional(NewVectorFromArray(l.Direction), color)
+	}
+
+	panic(fmt.Sprintf("Got invalid light type: %s", l.Type))
+}
+
+type EnvironmentInput struct {
+	File      string
+	Rotation  float64
+	Intensity float64
+}
+
+type SkyInput struct {
+	SunDirection [3]float64
+	Turbidity    float64
+	GroundAlbedo [3]float64
+	Intensity    float64
+}
+
+// getSky returns nil when no sun direction is given.
+func (s SkyInput) getSky() (*Sky, float64) {
+	if s.SunDirection == [3]float64{} {
+		return nil, 0
+	}
+	turbidity := s.Turbidity
+	if turbidity == 0 {
+		turbidity = 3
+	}
+	intensity := s.Intensity
+	if intensity == 0 {
+		intensity = 1
+	}
+	return NewSky(NewVectorFromArray(s.SunDirection), turbidity, NewVectorFromArray(s.GroundAlbedo)), intensity
 }
 
 type SceneInput struct {
 	Camera       CameraInput
 	Objects      ObjectsInput
+	Prototypes   map[string]ObjectsInput
 	AmbientLight [3]float64
+	Fog          FogInput
+	Lights       []LightInput
+	Environment  EnvironmentInput
+	Sky          SkyInput
 }
 
 type Specification struct {
@@ -96,32 +784,234 @@ type Scene struct {
 	Camera       *Camera
 	HitableList  *HitableList
 	AmbientLight *Vector
+	Fog          *Fog
+	Lights       *LightList
+	Environment  *Environment
 }
 
 func (w Specification) GetCamera() *Camera {
 	camera := w.Scene.Camera
-	return NewCamera(
+	c := NewCamera(
 		NewVectorFromArray(camera.LookFrom),
 		NewVectorFromArray(camera.LookAt),
 		NewVectorFromArray(camera.UpVector),
 		camera.FieldOfView,
 		camera.AspectRatio, camera.Aperture, camera.Focus)
+	c.ShutterOpen, c.ShutterClose = camera.ShutterOpen, camera.ShutterClose
+	return c
 }
 
 func (w Specification) GetHitableList() *HitableList {
+	camera := w.Scene.Camera
+	return w.Settings.groupHitables(w.getObjects(), camera.ShutterOpen, camera.ShutterClose)
+}
+
+func (o ObjectsInput) getHitables(prototypes *prototypeBuilder) []Hitable {
+	var objects []Hitable
+
+	for _, sphere := range o.Spheres {
+		objects = append(objects, sphere.getSphere())
+	}
+
+	for _, sphere := range o.MovingSpheres {
+		objects = append(objects, sphere.getMovingSphere())
+	}
+
+	for _, mesh := range o.Meshes {
+		objects = append(objects, mesh.getMesh())
+	}
+
+	for _, plane := range o.Planes {
+		objects = append(objects, plane.getPlane())
+	}
+
+	for _, disk := range o.Disks {
+		objects = append(objects, disk.getDisk())
+	}
+
+	for _, quad := range o.Quads {
+		objects = append(objects, quad.getQuad())
+	}
+
+	for _, rect := range o.Rects {
+		objects = append(objects, rect.getQuad())
+	}
+
+	for _, box := range o.Boxes {
+		objects = append(objects, box.getBox())
+	}
+
+	for _, cylinder := range o.Cylinders {
+		objects = append(objects, cylinder.getCylinder())
+	}
+
+	for _, cone := range o.Cones {
+		objects = append(objects, cone.getCone())
+	}
+
+	for _, torus := range o.Tori {
+		objects = append(objects, torus.getTorus())
+	}
+
+	for _, instance := range o.Instances {
+		objects = append(objects, NewInstance(prototypes.get(instance.Prototype), instance.getMatrix()))
+	}
+
+	for _, csg := range o.CSG {
+		objects = append(objects, csg.getCSG(prototypes))
+	}
+
+	for _, medium := range o.Media {
+		objects = append(objects, medium.getMedium(prototypes))
+	}
+
+	for _, sdf := range o.SDFs {
+		objects = append(objects, sdf.getSDF())
+	}
+
+	for _, heightfield := range o.Heightfields {
+		objects = append(objects, heightfield.getHeightfield())
+	}
+
+	return objects
+}
+
+// groupHitables collects objects into a list, moving all objects bounded
+// over the shutter interval t0 to t1 under a single BVH node when the
+// settings ask for one.
+func (s *Setting) groupHitables(objects []Hitable, t0, t1 float64) *HitableList {
 	world := HitableList{}
+	if !s.useBVH(len(objects)) {
+		world.List = objects
+		return &world
+	}
+
+	var bounded []Hitable
+	for _, object := range objects {
+		if ok, _ := object.BoundingBox(t0, t1); ok {
+			bounded = append(bounded, object)
+		} else {
+			world.AddHitable(object)
+		}
+	}
 
-	for _, sphere := range w.Scene.Objects.Spheres {
-		world.AddHitable(sphere.getSphere())
+	if len(bounded) > 0 {
+		world.AddHitable(NewBVHNode(bounded, t0, t1))
 	}
 
 	return &world
 }
 
+// prototypeBuilder builds each named prototype once, on first use, so every
+// instance of it shares the same geometry.
+type prototypeBuilder struct {
+	inputs                    map[string]ObjectsInput
+	settings                  *Setting
+	shutterOpen, shutterClose float64
+	built                     map[string]Hitable
+	building                  map[string]bool
+}
+
+func (p *prototypeBuilder) get(name string) Hitable {
+	if hitable, ok := p.built[name]; ok {
+		return hitable
+	}
+
+	input, ok := p.inputs[name]
+	if !ok {
+		panic(fmt.Sprintf("Got unknown prototype: %s", name))
+	}
+	if p.building[name] {
+		panic(fmt.Sprintf("Prototype %s instances itself", name))
+	}
+
+	p.building[name] = true
+	list := p.settings.groupHitables(input.getHitables(p), p.shutterOpen, p.shutterClose)
+	delete(p.building, name)
+
+	var hitable Hitable = list
+	if len(list.List) == 1 {
+		hitable = list.List[0]
+	}
+	p.built[name] = hitable
+
+	return hitable
+}
+
+func (w Specification) getObjects() []Hitable {
+	camera := w.Scene.Camera
+	prototypes := &prototypeBuilder{
+		inputs:       w.Scene.Prototypes,
+		settings:     &w.Settings,
+		shutterOpen:  camera.ShutterOpen,
+		shutterClose: camera.ShutterClose,
+		built:        make(map[string]Hitable),
+		building:     make(map[string]bool),
+	}
+
+	return w.Scene.Objects.getHitables(prototypes)
+}
+
+func (w Specification) GetFog() *Fog {
+	if w.Scene.Fog.Density <= 0 {
+		return nil
+	}
+	distance := w.Scene.Fog.Distance
+	if distance <= 0 {
+		distance = DefaultFogDistance
+	}
+	return NewFog(w.Scene.Fog.Density, distance, NewVectorFromArray(w.Scene.Fog.Albedo))
+}
+
+// getEnvironment returns the scene's environment map, or the given sky baked
+// into one, and nil when there is neither, leaving AmbientLight as the
+// background.
+func (w Specification) getEnvironment(sky *Sky, skyIntensity float64) *Environment {
+	input := w.Scene.Environment
+	if sky != nil {
+		if input.File != "" {
+			panic("Scene can't have both an Environment and a Sky")
+		}
+		return sky.Environment(skyIntensity)
+	}
+	if input.File == "" {
+		return nil
+	}
+	environmentMap, err := LoadEnvironmentImage(input.File)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to load environment map: %v", err))
+	}
+	intensity := input.Intensity
+	if intensity == 0 {
+		intensity = 1
+	}
+	return NewEnvironment(environmentMap, input.Rotation, intensity)
+}
+
 func (w Specification) GetScene() *Scene {
+	camera := w.Scene.Camera
+	objects := w.getObjects()
+	sky, skyIntensity := w.Scene.Sky.getSky()
+	environment := w.getEnvironment(sky, skyIntensity)
+	lights := NewLightList(objects)
+	if w.Image.HasAOV(ObjectIDAOV) {
+		objects = identify(objects)
+	}
+	if environment != nil && environment.CanSample() {
+		lights.Environment = environment
+	}
+	if sky != nil {
+		lights.Delta = append(lights.Delta, sky.Sun(skyIntensity))
+	}
+	for _, light := range w.Scene.Lights {
+		lights.Delta = append(lights.Delta, light.getLight())
+	}
 	return &Scene{
 		Camera:       w.GetCamera(),
-		HitableList:  w.GetHitableList(),
+		HitableList:  w.Settings.groupHitables(objects, camera.ShutterOpen, camera.ShutterClose),
 		AmbientLight: NewVectorFromArray(w.Scene.AmbientLight),
+		Fog:          w.GetFog(),
+		Lights:       lights,
+		Environment:  environment,
 	}
 }
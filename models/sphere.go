@@ -32,33 +32,115 @@ func (s *Sphere) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
 		a2 := 2 * a
 		root := (-b - sqrtD) / a2
 		if root > tmin && root < tmax {
-
-			tempP := r.PointAtParameter(root)
-
-			record := &HitRecord{
-				T:        root,
-				P:        tempP,
-				N:        tempP.Copy().SubtractVector(s.Center).MakeUnitVector(),
-				Material: s.Material,
-			}
-
-			return true, record
+			return true, s.hitRecord(r, root)
 		}
 		root = (-b + sqrtD) / a2
 		if root > tmin && root < tmax {
-			tempP := r.PointAtParameter(root)
+			return true, s.hitRecord(r, root)
+		}
+	}
+	return false, nil
+}
+
+func (s *Sphere) hitRecord(r *Ray, t float64) *HitRecord {
+	p := r.PointAtParameter(t)
+	n := p.Copy().SubtractVector(s.Center).MakeUnitVector()
+	u, v := SphereUV(n)
+	tangent := NewVector(n.Z(), 0, -n.X())
+
+	return &HitRecord{
+		T:         t,
+		P:         p,
+		N:         n,
+		U:         u,
+		V:         v,
+		Tangent:   tangent,
+		Bitangent: NewEmptyVector().VectorCrossProduct(n, tangent),
+		Material:  s.Material,
+	}
+}
+
+// SphereUV maps a point on the unit sphere to texture coordinates, with u
+// going around the Y axis and v running from the bottom pole to the top.
+func SphereUV(p *Vector) (float64, float64) {
+	theta := math.Acos(math.Max(-1, math.Min(1, -p.Y())))
+	phi := math.Atan2(-p.Z(), p.X()) + math.Pi
+	return phi / (2 * math.Pi), theta / math.Pi
+}
+
+// BoundingBox also encloses spheres with a negative radius, whose normals
+// point inwards.
+func (s *Sphere) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	radius := math.Abs(s.Radius)
+	r := NewVector(radius, radius, radius)
+	return true, NewAABB(
+		s.Center.Copy().SubtractVector(r),
+		s.Center.Copy().AddVector(r),
+	)
+}
+
+type SphereKeyframe struct {
+	Time   float64
+	Center *Vector
+}
 
-			record := &HitRecord{
-				T:        root,
-				P:        tempP,
-				N:        tempP.Copy().SubtractVector(s.Center).MakeUnitVector(),
-				Material: s.Material,
-			}
+// MovingSphere moves its center linearly between keyframes, which must be
+// sorted by time. Before the first and after the last keyframe it rests.
+type MovingSphere struct {
+	Keyframes []SphereKeyframe
+	Radius    float64
+	Material  Material
+}
 
-			return true, record
+func NewMovingSphere(keyframes []SphereKeyframe, r float64, material Material) *MovingSphere {
+	return &MovingSphere{
+		Keyframes: keyframes,
+		Radius:    r,
+		Material:  material,
+	}
+}
+
+func (m *MovingSphere) CenterAt(t float64) *Vector {
+	last := len(m.Keyframes) - 1
+	if t <= m.Keyframes[0].Time {
+		return m.Keyframes[0].Center
+	}
+	if t >= m.Keyframes[last].Time {
+		return m.Keyframes[last].Center
+	}
+
+	k := 1
+	for m.Keyframes[k].Time < t {
+		k++
+	}
+	k0, k1 := m.Keyframes[k-1], m.Keyframes[k]
+	f := (t - k0.Time) / (k1.Time - k0.Time)
+	return k0.Center.Copy().Scale(1-f).AddScaledVector(k1.Center, f)
+}
+
+func (m *MovingSphere) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	sphere := Sphere{
+		Center:   m.CenterAt(r.Time),
+		Radius:   m.Radius,
+		Material: m.Material,
+	}
+	return sphere.Hit(r, tmin, tmax)
+}
+
+// BoundingBox encloses the sphere at both ends of the interval and at every
+// keyframe in between, which bounds the linear motion between them.
+func (m *MovingSphere) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	_, box := (&Sphere{Center: m.CenterAt(t0), Radius: m.Radius}).BoundingBox(t0, t1)
+	_, endBox := (&Sphere{Center: m.CenterAt(t1), Radius: m.Radius}).BoundingBox(t0, t1)
+	box = SurroundingBox(box, endBox)
+
+	for _, keyframe := range m.Keyframes {
+		if keyframe.Time > t0 && keyframe.Time < t1 {
+			_, keyBox := (&Sphere{Center: keyframe.Center, Radius: m.Radius}).BoundingBox(t0, t1)
+			box = SurroundingBox(box, keyBox)
 		}
 	}
-	return false, nil
+	return true, box
 }
 
 func RandomPointInUnitSphere(rng *rand.Rand) *Vector {
@@ -74,3 +156,7 @@ func RandomPointInUnitSphere(rng *rand.Rand) *Vector {
 
 	return p
 }
+
+func RandomUnitVector(rng *rand.Rand) *Vector {
+	return RandomPointInUnitSphere(rng).MakeUnitVector()
+}
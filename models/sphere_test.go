@@ -0,0 +1,33 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSphereBoundingBox(t *testing.T) {
+	tests := []struct {
+		name   string
+		object Hitable
+	}{
+		{"sphere", NewSphere(1, 2, 3, 0.5, nil)},
+		{"negative radius", NewSphere(1, 2, 3, -0.5, nil)},
+		{"moving negative radius", NewMovingSphere([]SphereKeyframe{{Time: 0, Center: NewVector(1, 2, 3)}}, -0.5, nil)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, box := test.object.BoundingBox(0, 1)
+			want := NewAABB(NewVector(0.5, 1.5, 2.5), NewVector(1.5, 2.5, 3.5))
+			if box.Min.Copy().SubtractVector(want.Min).Length() > 1e-12 || box.Max.Copy().SubtractVector(want.Max).Length() > 1e-12 {
+				t.Errorf("got box %v to %v, want %v to %v", box.Min, box.Max, want.Min, want.Max)
+			}
+
+			// A ray through the centre must reach the sphere inside its box.
+			r := &Ray{Origin: NewVector(1, 2, -5), Direction: NewVector(0, 0, 1)}
+			if !box.Hit(r, 0, math.MaxFloat64) {
+				t.Error("box rejects a ray through the sphere")
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+)
+
+const (
+	ConstantTexture   = "Constant"
+	CheckerTexture    = "Checker"
+	ImageTexture      = "Image"
+	NoiseTexture      = "Noise"
+	TurbulenceTexture = "Turbulence"
+	MarbleTexture     = "Marble"
+)
+
+// Texture gives the colour of a surface at texture coordinates (u, v) and
+// world point p. Implementations must not modify p and return a new vector.
+type Texture interface {
+	Value(u, v float64, p *Vector) *Vector
+}
+
+type SolidTexture struct {
+	Color *Vector
+}
+
+func NewSolidTexture(color *Vector) *SolidTexture {
+	return &SolidTexture{Color: color}
+}
+
+func (s *SolidTexture) Value(u, v float64, p *Vector) *Vector {
+	return s.Color.Copy()
+}
+
+// Checker alternates between Odd and Even in cells of the given Size, either
+// in world space or, when UV is set, in texture space.
+type Checker struct {
+	Odd, Even Texture
+	Size      float64
+	UV        bool
+}
+
+func NewChecker(odd, even Texture, size float64, uv bool) *Checker {
+	return &Checker{
+		Odd:  odd,
+		Even: even,
+		Size: size,
+		UV:   uv,
+	}
+}
+
+func (c *Checker) Value(u, v float64, p *Vector) *Vector {
+	var cell float64
+	if c.UV {
+		cell = math.Floor(u/c.Size) + math.Floor(v/c.Size)
+	} else {
+		cell = math.Floor(p.data[0]/c.Size) + math.Floor(p.data[1]/c.Size) + math.Floor(p.data[2]/c.Size)
+	}
+	if math.Mod(cell, 2) == 0 {
+		return c.Even.Value(u, v, p)
+	}
+	return c.Odd.Value(u, v, p)
+}
+
+// Image repeats a picture across texture space, with (0, 0) at the bottom
+// left of the picture.
+type Image struct {
+	Width, Height int
+	Pixels        []*Vector
+}
+
+// LoadImage reads a PNG or JPEG file. Pixel values are decoded from sRGB to
+// linear, undoing the curve applied to rendered images, so a texture renders
+// as it looks.
+func LoadImage(filePath string) (*Image, error) {
+	return loadImage(filePath, true)
+}
+
+// LoadImageData reads a PNG or JPEG file holding data rather than colours,
+// such as a normal or bump map, keeping pixel values as they are.
+func LoadImageData(filePath string) (*Image, error) {
+	return loadImage(filePath, false)
+}
+
+func loadImage(filePath string, linearize bool) (*Image, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	bounds := img.Bounds()
+	texture := &Image{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Pixels: make([]*Vector, bounds.Dx()*bounds.Dy()),
+	}
+	for j := 0; j < texture.Height; j++ {
+		for i := 0; i < texture.Width; i++ {
+			r, g, b, _ := img.At(bounds.Min.X+i, bounds.Min.Y+j).RGBA()
+			color := NewVector(float64(r), float64(g), float64(b)).Scale(1.0 / 0xffff)
+			if linearize {
+				for k, x := range color.data {
+					color.data[k] = sRGBToLinear(x)
+				}
+			}
+			texture.Pixels[j*texture.Width+i] = color
+		}
+	}
+	return texture, nil
+}
+
+func (t *Image) Value(u, v float64, p *Vector) *Vector {
+	u -= math.Floor(u)
+	v -= math.Floor(v)
+	i := minInt(int(u*float64(t.Width)), t.Width-1)
+	j := minInt(int((1-v)*float64(t.Height)), t.Height-1)
+	return t.Pixels[j*t.Width+i].Copy()
+}
+
+// Sample filters bilinearly between the four pixels around (u, v), which
+// keeps gradients of data images smooth.
+func (t *Image) Sample(u, v float64) *Vector {
+	x := (u-math.Floor(u))*float64(t.Width) - 0.5
+	y := (1-v+math.Floor(v))*float64(t.Height) - 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+
+	color := NewEmptyVector()
+	for _, corner := range [4][3]float64{{0, 0, (1 - fx) * (1 - fy)}, {1, 0, fx * (1 - fy)}, {0, 1, (1 - fx) * fy}, {1, 1, fx * fy}} {
+		i := wrapIndex(int(x0)+int(corner[0]), t.Width)
+		j := wrapIndex(int(y0)+int(corner[1]), t.Height)
+		color.AddScaledVector(t.Pixels[j*t.Width+i], corner[2])
+	}
+	return color
+}
+
+func wrapIndex(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+// Noise shades Color by Perlin noise of the point scaled by Scale.
+type Noise struct {
+	Perlin *Perlin
+	Scale  float64
+	Color  *Vector
+}
+
+func (n *Noise) Value(u, v float64, p *Vector) *Vector {
+	q := p.Copy().Scale(n.Scale)
+	return n.Color.Copy().Scale(0.5 * (1 + n.Perlin.Noise(q)))
+}
+
+// Turbulence shades Color by Depth octaves of Perlin noise.
+type Turbulence struct {
+	Perlin *Perlin
+	Scale  float64
+	Depth  int
+	Color  *Vector
+}
+
+func (t *Turbulence) Value(u, v float64, p *Vector) *Vector {
+	q := p.Copy().Scale(t.Scale)
+	return t.Color.Copy().Scale(t.Perlin.Turbulence(q, t.Depth))
+}
+
+// Marble runs sine bands along Z, displaced by turbulence, to look like
+// veined stone.
+type Marble struct {
+	Perlin *Perlin
+	Scale  float64
+	Depth  int
+	Color  *Vector
+}
+
+func (m *Marble) Value(u, v float64, p *Vector) *Vector {
+	q := p.Copy().Scale(m.Scale)
+	phase := q.data[2] + 10*m.Perlin.Turbulence(q, m.Depth)
+	return m.Color.Copy().Scale(0.5 * (1 + math.Sin(phase)))
+}
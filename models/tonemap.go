@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	ClampToneMapping            = "Clamp"
+	ReinhardToneMapping         = "Reinhard"
+	ExtendedReinhardToneMapping = "ExtendedReinhard"
+	ACESToneMapping             = "ACES"
+	HableToneMapping            = "Hable"
+)
+
+const (
+	SRGBTransfer   = "sRGB"
+	Gamma2Transfer = "Gamma2"
+	LinearTransfer = "Linear"
+)
+
+// hableWhitePoint is the linear value Hable's curve maps to white unless told
+// otherwise.
+const hableWhitePoint = 11.2
+
+// ToneMapper turns linear colours into display values in [0, 1]. Colours are
+// scaled by 2^Exposure, compressed by Operator into [0, 1] and encoded with
+// the Transfer curve. WhitePoint is the linear value the extended Reinhard
+// and Hable operators map to white.
+type ToneMapper struct {
+	Operator   string
+	Exposure   float64
+	WhitePoint float64
+	Transfer   string
+}
+
+// GetToneMapper returns the tone mapper the image asks for, defaulting to a
+// plain clamp and the sRGB curve. A zero WhitePoint is left for Quantize to
+// fill in.
+func (i *ImageInput) GetToneMapper() *ToneMapper {
+	mapper := &ToneMapper{
+		Operator:   i.ToneMapping,
+		Exposure:   i.Exposure,
+		WhitePoint: i.WhitePoint,
+		Transfer:   i.Transfer,
+	}
+	if mapper.Operator == "" {
+		mapper.Operator = ClampToneMapping
+	}
+	if mapper.Transfer == "" {
+		mapper.Transfer = SRGBTransfer
+	}
+
+	switch mapper.Operator {
+	case ClampToneMapping, ReinhardToneMapping, ExtendedReinhardToneMapping, ACESToneMapping, HableToneMapping:
+	default:
+		panic(fmt.Sprintf("Got invalid tone mapping: %s", mapper.Operator))
+	}
+	switch mapper.Transfer {
+	case SRGBTransfer, Gamma2Transfer, LinearTransfer:
+	default:
+		panic(fmt.Sprintf("Got invalid transfer: %s", mapper.Transfer))
+	}
+	return mapper
+}
+
+// Map returns the display value of a linear colour.
+func (m *ToneMapper) Map(color *Vector) *Vector {
+	c := color.Copy().Scale(math.Exp2(m.Exposure))
+	for k := range c.data {
+		c.data[k] = math.Max(0, c.data[k])
+	}
+
+	switch m.Operator {
+	case ReinhardToneMapping:
+		l := luminance(c)
+		c.Scale(1 / (1 + l))
+	case ExtendedReinhardToneMapping:
+		l := luminance(c)
+		white2 := m.WhitePoint * m.WhitePoint
+		c.Scale((1 + l/white2) / (1 + l))
+	case ACESToneMapping:
+		for k, x := range c.data {
+			c.data[k] = x * (2.51*x + 0.03) / (x*(2.43*x+0.59) + 0.14)
+		}
+	case HableToneMapping:
+		white := hable(m.WhitePoint)
+		for k, x := range c.data {
+			c.data[k] = hable(x) / white
+		}
+	}
+
+	for k, x := range c.data {
+		x = math.Min(1, x)
+		switch m.Transfer {
+		case Gamma2Transfer:
+			c.data[k] = math.Sqrt(x)
+		case LinearTransfer:
+			c.data[k] = x
+		default:
+			c.data[k] = linearToSRGB(x)
+		}
+	}
+	return c
+}
+
+// hable is John Hable's filmic curve from Uncharted 2.
+func hable(x float64) float64 {
+	const a, b, c, d, e, f = 0.15, 0.50, 0.10, 0.20, 0.02, 0.30
+	return (x*(a*x+c*b)+d*e)/(x*(a*x+b)+d*f) - e/f
+}
+
+// linearToSRGB applies the sRGB transfer function to a value in [0, 1].
+func linearToSRGB(x float64) float64 {
+	if x <= 0.0031308 {
+		return 12.92 * x
+	}
+	return 1.055*math.Pow(x, 1/2.4) - 0.055
+}
+
+// sRGBToLinear undoes linearToSRGB.
+func sRGBToLinear(x float64) float64 {
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
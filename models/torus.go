@@ -0,0 +1,99 @@
+package models
+
+import (
+	"math"
+
+	"github.com/DheerendraRathor/GoTracer/utils"
+)
+
+// Torus lies around its local +Y axis with the tube centered on a circle of
+// MajorRadius in the XZ plane.
+type Torus struct {
+	Frame       *Frame
+	MajorRadius float64
+	MinorRadius float64
+	Material    Material
+}
+
+func NewTorus(center, axis *Vector, majorRadius, minorRadius float64, material Material) *Torus {
+	return &Torus{
+		Frame:       NewFrame(center, axis),
+		MajorRadius: majorRadius,
+		MinorRadius: minorRadius,
+		Material:    material,
+	}
+}
+
+func (to *Torus) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	local := to.Frame.ToLocalRay(r)
+
+	// Work with a unit direction starting close to the bounding sphere so the
+	// quartic coefficients stay well conditioned.
+	scale := local.Direction.Length()
+	d := local.Direction.Copy().Scale(1 / scale)
+	bound := to.MajorRadius + to.MinorRadius
+	od := local.Origin.Dot(d)
+	disc := od*od - local.Origin.SquaredLength() + bound*bound
+	if disc <= 0 {
+		return false, nil
+	}
+	shift := math.Max(0, -od-math.Sqrt(disc))
+	o := local.Origin.Copy().AddScaledVector(d, shift)
+
+	R2 := to.MajorRadius * to.MajorRadius
+	e := o.SquaredLength() + R2 - to.MinorRadius*to.MinorRadius
+	f := o.Dot(d)
+	ox, oz, dx, dz := o.data[0], o.data[2], d.data[0], d.data[2]
+
+	roots := utils.SolveQuartic(
+		1,
+		4*f,
+		4*f*f+2*e-4*R2*(dx*dx+dz*dz),
+		4*e*f-8*R2*(ox*dx+oz*dz),
+		e*e-4*R2*(ox*ox+oz*oz),
+	)
+
+	hit := false
+	closest := tmax
+	for _, root := range roots {
+		t := (root + shift) / scale
+		if t > tmin && t < closest {
+			hit = true
+			closest = t
+		}
+	}
+	if !hit {
+		return false, nil
+	}
+
+	p := local.Origin.Copy().AddScaledVector(local.Direction, closest)
+	x, y, z := p.data[0], p.data[1], p.data[2]
+	s := p.SquaredLength() + R2 - to.MinorRadius*to.MinorRadius
+	n := NewVector(x*(s-2*R2), y*s, z*(s-2*R2)).MakeUnitVector()
+
+	ringDistance := math.Sqrt(x*x+z*z) - to.MajorRadius
+	tangent := NewVector(-z, 0, x)
+
+	return true, &HitRecord{
+		T:         closest,
+		P:         r.PointAtParameter(closest),
+		N:         to.Frame.ToWorldDirection(n),
+		U:         azimuthU(x, z),
+		V:         (math.Atan2(y, ringDistance) + math.Pi) / (2 * math.Pi),
+		Tangent:   to.Frame.ToWorldDirection(tangent),
+		Bitangent: to.Frame.ToWorldDirection(NewEmptyVector().VectorCrossProduct(tangent, n)),
+		Material:  to.Material,
+	}
+}
+
+func (to *Torus) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	extent := NewEmptyVector()
+	for a := 0; a < 3; a++ {
+		ya := to.Frame.Y.data[a]
+		extent.data[a] = to.MajorRadius*math.Sqrt(math.Max(0, 1-ya*ya)) + to.MinorRadius
+	}
+	return true, NewAABB(
+		to.Frame.Origin.Copy().SubtractVector(extent),
+		to.Frame.Origin.Copy().AddVector(extent),
+	)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTorusHitScaledRay(t *testing.T) {
+	torus := NewTorus(NewVector(0, 0, 0), NewVector(0, 1, 0), 1, 0.25, nil)
+	direction := NewVector(1, -0.03, 0.05).MakeUnitVector()
+
+	for _, scale := range []float64{1, 0.5, 3} {
+		r := &Ray{Origin: NewVector(-3, 0.1, 0), Direction: direction.Copy().Scale(scale)}
+		hit, record := torus.Hit(r, 0, math.MaxFloat64)
+		if !hit {
+			t.Fatalf("scale %v: missed the torus", scale)
+		}
+
+		// The hit point lies on the tube and the normal points away from the
+		// ring circle.
+		p := record.P
+		ring := NewVector(p.X(), 0, p.Z()).MakeUnitVector()
+		fromRing := p.Copy().SubtractVector(ring)
+		if math.Abs(fromRing.Length()-0.25) > 1e-9 {
+			t.Errorf("scale %v: hit point %v is %v from the ring, want 0.25", scale, p, fromRing.Length())
+		}
+		if n := fromRing.MakeUnitVector(); n.Copy().SubtractVector(record.N).Length() > 1e-9 {
+			t.Errorf("scale %v: got normal %v, want %v", scale, record.N, n)
+		}
+	}
+}
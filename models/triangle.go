@@ -0,0 +1,126 @@
+package models
+
+import "math"
+
+const (
+	triangleEpsilon    = 1e-9
+	triangleBoxPadding = 1e-4
+)
+
+type Triangle struct {
+	Vertices [3]*Vector
+	Normals  [3]*Vector
+	UVs      [3][2]float64
+	HasUV    bool
+	Colors   [3]*Vector
+	Material Material
+}
+
+func NewTriangle(v0, v1, v2 *Vector, material Material) *Triangle {
+	return &Triangle{
+		Vertices: [3]*Vector{v0, v1, v2},
+		Material: material,
+	}
+}
+
+func (t *Triangle) IsSmooth() bool {
+	return t.Normals[0] != nil && t.Normals[1] != nil && t.Normals[2] != nil
+}
+
+// Hit uses the Möller–Trumbore algorithm, which yields the barycentric
+// coordinates of the hit point along with the ray parameter.
+func (t *Triangle) Hit(r *Ray, tmin, tmax float64) (bool, *HitRecord) {
+	e1 := t.Vertices[1].Copy().SubtractVector(t.Vertices[0])
+	e2 := t.Vertices[2].Copy().SubtractVector(t.Vertices[0])
+
+	p := NewEmptyVector().VectorCrossProduct(r.Direction, e2)
+	det := e1.Dot(p)
+	if math.Abs(det) < triangleEpsilon {
+		return false, nil
+	}
+	invDet := 1 / det
+
+	s := r.Origin.Copy().SubtractVector(t.Vertices[0])
+	b1 := s.Dot(p) * invDet
+	if b1 < 0 || b1 > 1 {
+		return false, nil
+	}
+
+	q := NewEmptyVector().VectorCrossProduct(s, e1)
+	b2 := r.Direction.Dot(q) * invDet
+	if b2 < 0 || b1+b2 > 1 {
+		return false, nil
+	}
+
+	root := e2.Dot(q) * invDet
+	if root <= tmin || root >= tmax {
+		return false, nil
+	}
+	b0 := 1 - b1 - b2
+
+	var normal *Vector
+	if t.IsSmooth() {
+		normal = t.Normals[0].Copy().Scale(b0).
+			AddScaledVector(t.Normals[1], b1).
+			AddScaledVector(t.Normals[2], b2).
+			MakeUnitVector()
+	} else {
+		normal = NewEmptyVector().VectorCrossProduct(e1, e2).MakeUnitVector()
+	}
+
+	u, v := b1, b2
+	tangent, bitangent := e1, e2
+	if t.HasUV {
+		u = b0*t.UVs[0][0] + b1*t.UVs[1][0] + b2*t.UVs[2][0]
+		v = b0*t.UVs[0][1] + b1*t.UVs[1][1] + b2*t.UVs[2][1]
+		tangent, bitangent = t.uvTangents(e1, e2)
+	}
+
+	var color *Vector
+	if t.Colors[0] != nil {
+		color = t.Colors[0].Copy().Scale(b0).
+			AddScaledVector(t.Colors[1], b1).
+			AddScaledVector(t.Colors[2], b2)
+	}
+
+	return true, &HitRecord{
+		T:         root,
+		P:         r.PointAtParameter(root),
+		N:         normal,
+		U:         u,
+		V:         v,
+		Tangent:   tangent,
+		Bitangent: bitangent,
+		Color:     color,
+		Material:  t.Material,
+	}
+}
+
+// uvTangents solves for the directions of increasing u and v across the
+// triangle from its edges and their texture coordinate differences. Without
+// distinct texture coordinates it falls back to the edges themselves.
+func (t *Triangle) uvTangents(e1, e2 *Vector) (*Vector, *Vector) {
+	du1, dv1 := t.UVs[1][0]-t.UVs[0][0], t.UVs[1][1]-t.UVs[0][1]
+	du2, dv2 := t.UVs[2][0]-t.UVs[0][0], t.UVs[2][1]-t.UVs[0][1]
+	det := du1*dv2 - du2*dv1
+	if math.Abs(det) < triangleEpsilon {
+		return e1, e2
+	}
+
+	f := 1 / det
+	tangent := e1.Copy().Scale(dv2*f).AddScaledVector(e2, -dv1*f)
+	bitangent := e2.Copy().Scale(du1*f).AddScaledVector(e1, -du2*f)
+	return tangent, bitangent
+}
+
+func (t *Triangle) BoundingBox(t0, t1 float64) (bool, *AABB) {
+	min := t.Vertices[0].Copy()
+	max := t.Vertices[0].Copy()
+	for _, vertex := range t.Vertices[1:] {
+		for a := 0; a < 3; a++ {
+			min.data[a] = math.Min(min.data[a], vertex.data[a])
+			max.data[a] = math.Max(max.data[a], vertex.data[a])
+		}
+	}
+	return true, NewAABB(min, max).Pad(triangleBoxPadding)
+}
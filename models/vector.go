@@ -147,3 +147,14 @@ func (v *Vector) VectorCrossProduct(v1, v2 *Vector) *Vector {
 
 	return v
 }
+
+// OrthonormalBasis returns two unit vectors which, together with the unit
+// vector n, form a right handed orthonormal basis.
+func OrthonormalBasis(n *Vector) (*Vector, *Vector) {
+	sign := math.Copysign(1, n.data[2])
+	a := -1 / (sign + n.data[2])
+	b := n.data[0] * n.data[1] * a
+	t := NewVector(1+sign*n.data[0]*n.data[0]*a, sign*b, -sign*n.data[0])
+	s := NewVector(b, sign+n.data[1]*n.data[1]*a, -n.data[1])
+	return t, s
+}
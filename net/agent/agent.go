@@ -145,16 +145,28 @@ func (c *RenderingClient) ResultSender() {
 		Type: messages.PixelResult,
 	}
 
+	// After a failed write the results are still drained so the tracer
+	// doesn't block on a full channel.
+	failed := false
 	for pixel := range c.Results {
 		message.OperationId = c.OperationId
 		if pixel == nil {
 			message.Type = messages.RenderingCompleted
-			c.Conn.WriteJSON(message)
-			break
+		} else {
+			pixel.MakeFinite()
+			message.Data = pixel
+		}
+
+		if !failed {
+			if err := c.Conn.WriteJSON(message); err != nil {
+				log.Printf("Unable to send result: %s", err)
+				failed = true
+			}
 		}
 
-		message.Data = pixel
-		c.Conn.WriteJSON(message)
+		if pixel == nil {
+			break
+		}
 	}
 }
 
@@ -4,9 +4,6 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
 	"io/ioutil"
 	"log"
 	"net"
@@ -19,7 +16,6 @@ import (
 
 	"github.com/DheerendraRathor/GoTracer/models"
 	"github.com/DheerendraRathor/GoTracer/net/constants"
-	"github.com/DheerendraRathor/GoTracer/utils"
 	"github.com/gorilla/websocket"
 	"gopkg.in/cheggaaa/pb.v1"
 )
@@ -79,7 +75,6 @@ func (a *Agent) Initialize() {
 	}
 
 	var message messages.WebSocketMessage
-	var pixelMessage messages.PixelResultMessage
 
 	for {
 		_, rawMsg, err := a.Conn.ReadMessage()
@@ -93,6 +88,9 @@ func (a *Agent) Initialize() {
 		messageType := message.Type
 		switch messageType {
 		case messages.PixelResult:
+			// A fresh message for each pixel keeps its AOVs from sharing memory
+			// with the pixels already sent on.
+			var pixelMessage messages.PixelResultMessage
 			json.Unmarshal(rawMsg, &pixelMessage)
 			pixel := pixelMessage.Data
 			a.ResultChannel <- pixel
@@ -122,11 +120,9 @@ func main() {
 
 	var env models.Specification
 	json.Unmarshal(specFile, &env)
+	env.Image.Validate()
 
-	pngImage := image.NewRGBA(image.Rectangle{
-		image.Point{0, 0},
-		image.Point{env.Image.Width, env.Image.Height},
-	})
+	framebuffers := models.NewFramebuffers(&env.Image)
 
 	connectedAgents := []*Agent{}
 
@@ -222,8 +218,7 @@ func main() {
 			select {
 			case pixel = <-renderedChannel:
 				progressBar.Increment()
-				rgbaColor := color.RGBA{pixel.Color[0], pixel.Color[1], pixel.Color[2], 255}
-				pngImage.Set(pixel.I, pixel.J, rgbaColor)
+				framebuffers.SetPixel(&pixel)
 			case agent := <-workDoneChannel:
 				agentsToWaitFor -= 1
 				log.Printf("Agent '%s' finished. Status: %t", agent.URL, agent.WorkDone)
@@ -236,8 +231,7 @@ func main() {
 
 	wg.Wait()
 
-	pngFile := utils.CreateNestedFile(env.Image.OutputFile)
-	defer pngFile.Close()
-
-	png.Encode(pngFile, pngImage)
+	if err := framebuffers.WriteFiles(&env.Image, env.Image.OutputFile); err != nil {
+		log.Fatalln("Unable to write image.", err)
+	}
 }
@@ -9,6 +9,12 @@ import (
 	"github.com/DheerendraRathor/GoTracer/models"
 )
 
+var withShapes bool
+
+func init() {
+	flag.BoolVar(&withShapes, "shapes", false, "Mix boxes, cylinders, cones and tori in with the small spheres")
+}
+
 func PositiveRandom() float64 {
 	return rand.Float64() * rand.Float64()
 }
@@ -20,16 +26,18 @@ func AnotherPositiveRandom() float64 {
 func main() {
 	flag.Parse()
 
-	var spheres []models.SphereInput
-	spheres = append(spheres, models.SphereInput{
-		Center: [3]float64{0, -1000, 0},
-		Radius: 1000,
+	var objects models.ObjectsInput
+	objects.Planes = append(objects.Planes, models.PlaneInput{
+		Point:  [3]float64{0, 0, 0},
+		Normal: [3]float64{0, 1, 0},
 		Surface: models.SurfaceInput{
 			Type:   models.LambertianMaterial,
 			Albedo: [3]float64{0.5, 0.5, 0.5},
 		},
 	})
 
+	var spheres []models.SphereInput
+
 	var matProb float64
 	var temp *models.Vector
 	var cleaner = models.NewVectorFromArray([3]float64{4, 0.2, 0})
@@ -41,25 +49,64 @@ func main() {
 
 			temp = models.NewVectorFromArray(center).SubtractVector(cleaner)
 			if temp.Length() > 0.9 {
-				sphere := models.SphereInput{
-					Center:  center,
-					Radius:  0.2,
-					Surface: models.SurfaceInput{},
-				}
+				surface := models.SurfaceInput{}
 				if matProb < 0.5 { //diffuse
-					sphere.Surface.Type = models.LambertianMaterial
-					sphere.Surface.Albedo = [3]float64{PositiveRandom(), PositiveRandom(), PositiveRandom()}
+					surface.Type = models.LambertianMaterial
+					surface.Albedo = [3]float64{PositiveRandom(), PositiveRandom(), PositiveRandom()}
 
 				} else if matProb < 0.9 { //Metal
-					sphere.Surface.Type = models.MetalMaterial
-					sphere.Surface.Albedo = [3]float64{AnotherPositiveRandom(), AnotherPositiveRandom(), AnotherPositiveRandom()}
-					sphere.Surface.Fuzz = 0.5 * rand.Float64()
+					surface.Type = models.MetalMaterial
+					surface.Albedo = [3]float64{AnotherPositiveRandom(), AnotherPositiveRandom(), AnotherPositiveRandom()}
+					surface.Fuzz = 0.5 * rand.Float64()
 				} else { //glass
-					sphere.Surface.Type = models.DielectricMaterial
-					sphere.Surface.Albedo = [3]float64{1.0, 1.0, 1.0}
-					sphere.Surface.RefIndex = (rand.Float64() * 0.5) + 1.5
+					surface.Type = models.DielectricMaterial
+					surface.Albedo = [3]float64{1.0, 1.0, 1.0}
+					surface.RefIndex = (rand.Float64() * 0.5) + 1.5
+				}
+
+				shape := 0
+				if withShapes {
+					shape = rand.Intn(5)
+				}
+				x, z := center[0], center[2]
+				switch shape {
+				case 0:
+					spheres = append(spheres, models.SphereInput{
+						Center:  center,
+						Radius:  0.2,
+						Surface: surface,
+					})
+				case 1:
+					objects.Boxes = append(objects.Boxes, models.BoxInput{
+						Min:     [3]float64{x - 0.17, 0, z - 0.17},
+						Max:     [3]float64{x + 0.17, 0.34, z + 0.17},
+						Surface: surface,
+					})
+				case 2:
+					objects.Cylinders = append(objects.Cylinders, models.CylinderInput{
+						Base:    [3]float64{x, 0, z},
+						Top:     [3]float64{x, 0.4, z},
+						Radius:  0.18,
+						Capped:  true,
+						Surface: surface,
+					})
+				case 3:
+					objects.Cones = append(objects.Cones, models.ConeInput{
+						Base:    [3]float64{x, 0, z},
+						Apex:    [3]float64{x, 0.45, z},
+						Radius:  0.2,
+						Capped:  true,
+						Surface: surface,
+					})
+				case 4:
+					objects.Tori = append(objects.Tori, models.TorusInput{
+						Center:      [3]float64{x, 0.07, z},
+						Axis:        [3]float64{0, 1, 0},
+						MajorRadius: 0.16,
+						MinorRadius: 0.07,
+						Surface:     surface,
+					})
 				}
-				spheres = append(spheres, sphere)
 			}
 		}
 	}
@@ -94,6 +141,8 @@ func main() {
 		},
 	)
 
-	jsonData, _ := json.MarshalIndent(spheres, "", "    ")
+	objects.Spheres = spheres
+
+	jsonData, _ := json.MarshalIndent(objects, "", "    ")
 	fmt.Println(string(jsonData))
 }
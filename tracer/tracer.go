@@ -12,8 +12,10 @@ import (
 
 var MaxRenderDepth int = 10
 
+// TracerOutput holds the linear colours of the traced pixels and of the AOVs,
+// before any quantisation for output.
 type TracerOutput struct {
-	Pixels [][]*models.Pixel
+	*models.Framebuffers
 }
 
 func GoTrace(
@@ -26,6 +28,7 @@ func GoTrace(
 	}
 
 	scene := env.GetScene()
+	aovs := env.Image.AOVs
 
 	width, height := env.Image.Width, env.Image.Height
 
@@ -49,10 +52,7 @@ func GoTrace(
 	var output *TracerOutput
 	if !sharePixelProgress {
 		output = &TracerOutput{
-			Pixels: make([][]*models.Pixel, imax),
-		}
-		for i := range output.Pixels {
-			output.Pixels[i] = make([]*models.Pixel, jmax)
+			Framebuffers: models.NewFramebuffers(&env.Image),
 		}
 	}
 
@@ -92,11 +92,11 @@ func GoTrace(
 
 			for _, point := range data {
 				i, j := point[0], point[1]
-				pixel := processPixel(i, j, width, height, samples, scene, rng)
+				pixel := processPixel(i, j, width, height, samples, scene, aovs, rng)
 				if sharePixelProgress {
 					progress <- pixel
 				} else {
-					output.Pixels[i][j] = pixel
+					output.SetPixel(pixel)
 				}
 			}
 		}(env.Image.Samples, scene, _data)
@@ -111,37 +111,196 @@ func GoTrace(
 	return output
 }
 
-func processPixel(i, j, imageWidth, imageHeight, sample int, scene *models.Scene, rng *rand.Rand) *models.Pixel {
+func processPixel(i, j, imageWidth, imageHeight, sample int, scene *models.Scene, aovs []string, rng *rand.Rand) *models.Pixel {
 	pixel := models.NewEmptyVector()
+	aovValues := make([]*models.Vector, len(aovs))
+	for k := range aovValues {
+		aovValues[k] = models.NewEmptyVector()
+	}
+
 	for s := 0; s < sample; s++ {
 		randFloatu, randFloatv := rng.Float64(), rng.Float64()
 		u, v := (float64(j)+randFloatu)/float64(imageWidth), (float64(i)+randFloatv)/float64(imageHeight)
 		ray := scene.Camera.RayAt(u, v, rng)
-		pixel.AddVector(getColor(ray, scene, 0, rng))
+		light := getColor(ray, scene, 0, rng, 1)
+		pixel.AddVector(light.total)
+
+		if len(aovs) > 0 {
+			addAOVs(aovValues, aovs, ray, light, scene.Camera, s == 0)
+		}
 	}
 
-	pixel.Scale(1 / float64(sample)).Gamma2()
-	uint8Pixel := pixel.ToPixel(j, imageHeight-i-1)
-	return uint8Pixel
+	result := pixel.Scale(1/float64(sample)).ToPixel(j, imageHeight-i-1)
+	for k, value := range aovValues {
+		if !models.IsIDAOV(aovs[k]) {
+			value.Scale(1 / float64(sample))
+		}
+		result.AOVs = append(result.AOVs, value.ToPixel(0, 0).Color)
+	}
+	return result
 }
 
-func getColor(r *models.Ray, scene *models.Scene, renderDepth int, rng *rand.Rand) *models.Vector {
+// addAOVs adds the value of each AOV for a camera ray to values. ID passes
+// keep the ID of the first sample of the pixel.
+func addAOVs(values []*models.Vector, aovs []string, ray *models.Ray, light radiance, camera *models.Camera, first bool) {
+	for k, name := range aovs {
+		switch {
+		case name == models.DirectAOV:
+			values[k].AddVector(light.total).SubtractVector(light.indirect)
+		case name == models.IndirectAOV:
+			values[k].AddVector(light.indirect)
+		case models.IsIDAOV(name):
+			if first {
+				values[k].AddVector(models.SurfaceAOV(name, ray, light.surface, camera))
+			}
+		default:
+			values[k].AddVector(models.SurfaceAOV(name, ray, light.surface, camera))
+		}
+	}
+}
+
+// radiance is the light arriving along a ray. Of the total, emitted is the
+// light emitted where the ray ends and indirect the light which scattered more
+// than once on its way to the ray's origin. surface is the first surface the
+// ray hit, if any, even when fog scattered it before.
+type radiance struct {
+	total, emitted, indirect *models.Vector
+	surface                  *models.HitRecord
+}
+
+// getColor traces r into the scene. At surfaces whose scattering can be
+// evaluated the lights are sampled directly as well, and emission found by
+// scattered rays is weighted by emissionWeight so that both ways of reaching
+// a light are combined by multiple importance sampling.
+func getColor(r *models.Ray, scene *models.Scene, renderDepth int, rng *rand.Rand, emissionWeight float64) radiance {
 
 	// tmin is 0.0001 to avoid self intersection
 	didHit, hitRecord := scene.HitableList.Hit(r, 0.0001, math.MaxFloat64)
+	surface := hitRecord
+
+	if scene.Fog != nil {
+		var tHit float64
+		if didHit {
+			tHit = hitRecord.T
+		}
+		if scattered, fogRecord := scene.Fog.Scatter(r, didHit, tHit, rng); scattered {
+			didHit, hitRecord = true, fogRecord
+			// Camera rays still report the surface behind the fog to the AOVs.
+			if surface != nil && renderDepth == 0 {
+				surface.Material.PerturbNormal(surface)
+			}
+		}
+	}
+
 	if didHit {
+		emitted := hitRecord.Material.Emitted(r, hitRecord).Scale(emissionWeight)
+		hitRecord.Material.PerturbNormal(hitRecord)
 		shouldScatter, attenuation, ray := hitRecord.Material.Scatter(r, hitRecord, rng)
 
-		if hitRecord.Material.IsLight() {
-			return attenuation
+		if renderDepth >= MaxRenderDepth || !shouldScatter {
+			return radiance{emitted, emitted, models.NewEmptyVector(), surface}
+		}
+
+		total := emitted.Copy()
+		weight := 1.0
+		if evaluator, ok := hitRecord.Material.(models.Evaluator); ok {
+			total.AddVector(deltaLights(r, hitRecord, evaluator, scene, rng))
+			if !scene.Lights.IsEmpty() {
+				total.AddVector(sampleLights(r, hitRecord, evaluator, scene, rng))
+				_, scatterPDF := evaluator.Evaluate(r, hitRecord, ray.Direction)
+				weight = powerHeuristic(scatterPDF, scene.Lights.DirectionPDF(hitRecord.P, ray.Direction))
+			}
 		}
 
-		if renderDepth < MaxRenderDepth && shouldScatter {
-			return attenuation.MultiplyVector(getColor(ray, scene, renderDepth+1, rng))
-		} else {
+		scattered := getColor(ray, scene, renderDepth+1, rng, weight)
+		indirect := scattered.total.Copy().SubtractVector(scattered.emitted).MultiplyVector(attenuation)
+		total.AddVector(attenuation.MultiplyVector(scattered.total))
+		return radiance{total, emitted, indirect, surface}
+	}
+
+	background := scene.AmbientLight
+	if scene.Environment != nil {
+		background = scene.Environment.Radiance(r.Direction).Scale(emissionWeight)
+	}
+	return radiance{background, background, models.NewEmptyVector(), surface}
+}
+
+// sampleLights returns the light reaching the hit along a direction sampled
+// towards the scene's lights, weighted against scattering towards it.
+func sampleLights(r *models.Ray, hitRecord *models.HitRecord, evaluator models.Evaluator, scene *models.Scene, rng *rand.Rand) *models.Vector {
+	direction := scene.Lights.SampleDirection(hitRecord.P, rng)
+	value, scatterPDF := evaluator.Evaluate(r, hitRecord, direction)
+	if value.SquaredLength() == 0 {
+		return value
+	}
+
+	shadow := &models.Ray{Origin: hitRecord.P, Direction: direction, Time: r.Time, Rand: rng}
+	didHit, lightRecord := scene.HitableList.Hit(shadow, 0.0001, math.MaxFloat64)
+	if !didHit && scene.Environment == nil {
+		return models.NewEmptyVector()
+	}
+	if scene.Fog != nil {
+		var tHit float64
+		if didHit {
+			tHit = lightRecord.T
+		}
+		if scattered, _ := scene.Fog.Scatter(shadow, didHit, tHit, rng); scattered {
 			return models.NewEmptyVector()
 		}
 	}
 
-	return scene.AmbientLight
+	lightPDF := scene.Lights.DirectionPDF(hitRecord.P, direction)
+	if lightPDF == 0 {
+		return models.NewEmptyVector()
+	}
+
+	var emitted *models.Vector
+	if didHit {
+		emitted = lightRecord.Material.Emitted(shadow, lightRecord)
+	} else {
+		emitted = scene.Environment.Radiance(direction)
+	}
+	return emitted.MultiplyVector(value).Scale(powerHeuristic(lightPDF, scatterPDF) / lightPDF)
+}
+
+// deltaLights returns the light reaching the hit from the scene's delta
+// lights which are not in shadow.
+func deltaLights(r *models.Ray, hitRecord *models.HitRecord, evaluator models.Evaluator, scene *models.Scene, rng *rand.Rand) *models.Vector {
+	total := models.NewEmptyVector()
+	for _, light := range scene.Lights.Delta {
+		direction, distance, incoming := light.Illuminate(hitRecord.P)
+		if incoming.SquaredLength() == 0 {
+			continue
+		}
+		value, _ := evaluator.Evaluate(r, hitRecord, direction)
+		if value.SquaredLength() == 0 {
+			continue
+		}
+
+		shadow := &models.Ray{Origin: hitRecord.P, Direction: direction, Time: r.Time, Rand: rng}
+		tmax := distance - 0.0001
+		if math.IsInf(distance, 1) {
+			tmax = math.MaxFloat64
+		}
+		if blocked, _ := scene.HitableList.Hit(shadow, 0.0001, tmax); blocked {
+			continue
+		}
+		if scene.Fog != nil {
+			if scattered, _ := scene.Fog.Scatter(shadow, !math.IsInf(distance, 1), distance, rng); scattered {
+				continue
+			}
+		}
+
+		total.AddVector(value.MultiplyVector(incoming))
+	}
+	return total
+}
+
+// powerHeuristic weights a sample taken with density pdf against another
+// strategy which would have taken it with density otherPDF.
+func powerHeuristic(pdf, otherPDF float64) float64 {
+	if pdf == 0 {
+		return 0
+	}
+	return pdf * pdf / (pdf*pdf + otherPDF*otherPDF)
 }
@@ -0,0 +1,121 @@
+package utils
+
+import "math"
+
+const polynomialEpsilon = 1e-9
+
+func isZero(x float64) bool {
+	return x > -polynomialEpsilon && x < polynomialEpsilon
+}
+
+// SolveQuadratic returns the real roots of a*x^2 + b*x + c = 0.
+func SolveQuadratic(a, b, c float64) []float64 {
+	p := b / (2 * a)
+	q := c / a
+	d := p*p - q
+
+	if isZero(d) {
+		return []float64{-p}
+	} else if d < 0 {
+		return nil
+	}
+
+	sqrtD := math.Sqrt(d)
+	return []float64{sqrtD - p, -sqrtD - p}
+}
+
+// SolveCubic returns the real roots of a*x^3 + b*x^2 + c*x + d = 0 using
+// Cardano's method.
+func SolveCubic(a, b, c, d float64) []float64 {
+	A, B, C := b/a, c/a, d/a
+
+	// Substitute x = y - A/3 to eliminate the quadratic term: y^3 + 3py + 2q = 0
+	sqA := A * A
+	p := (-sqA/3 + B) / 3
+	q := (2.0/27*A*sqA - A*B/3 + C) / 2
+
+	cbP := p * p * p
+	disc := q*q + cbP
+
+	var roots []float64
+	if isZero(disc) {
+		if isZero(q) {
+			roots = []float64{0}
+		} else {
+			u := math.Cbrt(-q)
+			roots = []float64{2 * u, -u}
+		}
+	} else if disc < 0 {
+		phi := math.Acos(-q/math.Sqrt(-cbP)) / 3
+		t := 2 * math.Sqrt(-p)
+		roots = []float64{
+			t * math.Cos(phi),
+			-t * math.Cos(phi+math.Pi/3),
+			-t * math.Cos(phi-math.Pi/3),
+		}
+	} else {
+		sqrtD := math.Sqrt(disc)
+		roots = []float64{math.Cbrt(sqrtD-q) - math.Cbrt(sqrtD+q)}
+	}
+
+	for i := range roots {
+		roots[i] -= A / 3
+	}
+	return roots
+}
+
+// SolveQuartic returns the real roots of a*x^4 + b*x^3 + c*x^2 + d*x + e = 0
+// using Ferrari's method. Each root is refined with Newton iterations since
+// the closed form loses precision for nearly tangent configurations.
+func SolveQuartic(a, b, c, d, e float64) []float64 {
+	A, B, C, D := b/a, c/a, d/a, e/a
+
+	// Substitute x = y - A/4 to eliminate the cubic term: y^4 + py^2 + qy + r = 0
+	sqA := A * A
+	p := -3.0/8*sqA + B
+	q := sqA*A/8 - A*B/2 + C
+	r := -3.0/256*sqA*sqA + sqA*B/16 - A*C/4 + D
+
+	var roots []float64
+	if isZero(r) {
+		roots = append(SolveCubic(1, 0, p, q), 0)
+	} else {
+		z := SolveCubic(1, -p/2, -r, r*p/2-q*q/8)[0]
+
+		u := z*z - r
+		v := 2*z - p
+		if isZero(u) {
+			u = 0
+		} else if u > 0 {
+			u = math.Sqrt(u)
+		} else {
+			return nil
+		}
+		if isZero(v) {
+			v = 0
+		} else if v > 0 {
+			v = math.Sqrt(v)
+		} else {
+			return nil
+		}
+
+		if q < 0 {
+			v = -v
+		}
+		roots = append(SolveQuadratic(1, v, z-u), SolveQuadratic(1, -v, z+u)...)
+	}
+
+	for i := range roots {
+		x := roots[i] - A/4
+		for k := 0; k < 2; k++ {
+			f := (((a*x+b)*x+c)*x+d)*x + e
+			df := ((4*a*x+3*b)*x+2*c)*x + d
+			if df == 0 {
+				break
+			}
+			x -= f / df
+		}
+		roots[i] = x
+	}
+	return roots
+}
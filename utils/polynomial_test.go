@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// distinctRoots sorts roots and drops repeats of a multiple root.
+func distinctRoots(roots []float64) []float64 {
+	sorted := append([]float64(nil), roots...)
+	sort.Float64s(sorted)
+	var distinct []float64
+	for _, root := range sorted {
+		if len(distinct) == 0 || root-distinct[len(distinct)-1] > 1e-6 {
+			distinct = append(distinct, root)
+		}
+	}
+	return distinct
+}
+
+func TestSolveQuartic(t *testing.T) {
+	tests := []struct {
+		name          string
+		a, b, c, d, e float64
+		want          []float64
+	}{
+		{"four roots", 1, -10, 35, -50, 24, []float64{1, 2, 3, 4}},
+		{"scaled", 2, -20, 70, -100, 48, []float64{1, 2, 3, 4}},
+		{"root at zero", 1, -2, -5, 6, 0, []float64{-2, 0, 1, 3}},
+		{"biquadratic", 1, 0, -3, 0, -4, []float64{-2, 2}},
+		{"torus section", 1, 0, -2.125, 0, 0.87890625, []float64{-1.25, -0.75, 0.75, 1.25}},
+		{"double roots", 1, 0, -2, 0, 1, []float64{-1, 1}},
+		{"no real roots", 1, 0, 0, 0, 1, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := distinctRoots(SolveQuartic(test.a, test.b, test.c, test.d, test.e))
+			if len(got) != len(test.want) {
+				t.Fatalf("got roots %v, want %v", got, test.want)
+			}
+			for k := range got {
+				if math.Abs(got[k]-test.want[k]) > 1e-6 {
+					t.Errorf("got roots %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}